@@ -0,0 +1,78 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"mime"
+	"strings"
+)
+
+const (
+	AcceptJson     = "application/json"
+	AcceptCsv      = "text/csv"
+	AcceptProtobuf = "application/x-protobuf"
+	AcceptZip      = "application/zip"
+)
+
+const (
+	ContentTypeMergePatch = "application/merge-patch+json"
+	ContentTypeJsonPatch  = "application/json-patch+json"
+)
+
+type acceptKey struct{}
+
+type contentTypeKey struct{}
+
+// WithAccept returns a context whose calls send mediaTypes as the Accept
+// header, e.g. WithAccept(ctx, AcceptCsv) for a report in CSV. A HeaderFunc
+// may still override it.
+func WithAccept(ctx context.Context, mediaTypes ...string) context.Context {
+	return context.WithValue(ctx, acceptKey{}, strings.Join(mediaTypes, ", "))
+}
+
+// AcceptFromContext returns the Accept header set by WithAccept, or "".
+func AcceptFromContext(ctx context.Context) string {
+	accept, _ := ctx.Value(acceptKey{}).(string)
+	return accept
+}
+
+// WithContentType returns a context whose calls send request bodies with
+// mediaType as the Content-Type header. A HeaderFunc may still override it.
+func WithContentType(ctx context.Context, mediaType string) context.Context {
+	return context.WithValue(ctx, contentTypeKey{}, mediaType)
+}
+
+// ContentTypeFromContext returns the Content-Type set by WithContentType, or
+// "".
+func ContentTypeFromContext(ctx context.Context) string {
+	contentType, _ := ctx.Value(contentTypeKey{}).(string)
+	return contentType
+}
+
+// isJsonContentType reports whether contentType is JSON or absent, in which
+// case a body is assumed to be JSON as it always has been.
+func isJsonContentType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"log/slog"
+	"math/rand"
+	"time"
+)
+
+// AccessLogSampling sets the fraction, from 0 to 1, of calls that are logged.
+type AccessLogSampling struct {
+	Success float64
+	Error   float64
+}
+
+// DefaultAccessLogSampling logs 1% of successful calls and every failure.
+var DefaultAccessLogSampling = AccessLogSampling{Success: 0.01, Error: 1}
+
+// AccessLogMiddleware writes one structured line per sampled call to logger:
+// method, path, status, duration, any WithMeta values, and, for failures, the
+// error. Successful calls are logged at Info and failures at Warn. A nil
+// logger means slog.Default(), as it is when each line is written.
+func AccessLogMiddleware(logger *slog.Logger, sampling AccessLogSampling) Middleware {
+	return func(next CallFunc) CallFunc {
+		return func(ctx context.Context, request *ApiRequest, headersFunc HeaderFunc) *ApiResponse {
+			start := time.Now()
+			response := next(ctx, request, headersFunc)
+			duration := time.Since(start)
+
+			rate, level := sampling.Success, slog.LevelInfo
+			if response.Err() != nil {
+				rate, level = sampling.Error, slog.LevelWarn
+			}
+			if rate <= 0 || (rate < 1 && rand.Float64() >= rate) {
+				return response
+			}
+
+			attrs := []slog.Attr{
+				slog.String("method", request.HttpMethod),
+				slog.String("path", request.Path),
+				slog.Int("status", response.StatusCode()),
+				slog.Duration("duration", duration),
+			}
+			if response.Stale() {
+				attrs = append(attrs, slog.Bool("stale", true))
+			}
+			if response.Err() != nil {
+				attrs = append(attrs, slog.String("error", response.Err().Message))
+				if reason := CancelReasonOf(response.Err()); reason != CancelReasonNone {
+					attrs = append(attrs, slog.String("cancel_reason", reason.String()))
+				}
+			}
+			if rate < 1 {
+				attrs = append(attrs, slog.Float64("sample_rate", rate))
+			}
+			if meta := MetaValues(ctx); meta != nil {
+				group := make([]any, 0, len(meta))
+				for _, name := range metaNames(meta) {
+					group = append(group, slog.Any(name, meta[name]))
+				}
+				attrs = append(attrs, slog.Group("meta", group...))
+			}
+
+			out := logger
+			if out == nil {
+				out = slog.Default()
+			}
+			out.LogAttrs(ctx, level, "http request", attrs...)
+			return response
+		}
+	}
+}
@@ -0,0 +1,41 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestAccessLogMiddlewareDefaultsToSlogDefault(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	defer slog.SetDefault(previous)
+
+	var calls atomic.Int32
+	middleware := AccessLogMiddleware(nil, AccessLogSampling{Success: 1, Error: 1})
+	middleware(countingCall(&calls))(context.Background(), newOrderRequest(), nil)
+
+	if line := buf.String(); !strings.Contains(line, "http request") || !strings.Contains(line, "path=/orders") {
+		t.Fatalf("default logger got %q, want the access log line", line)
+	}
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ArchiveOptions controls how ExtractZip holds an archive while reading it.
+// Zip archives keep their index at the end, so the whole archive must be
+// available before any entry can be read.
+type ArchiveOptions struct {
+	// MaxMemory is the largest archive held in memory. Defaults to 64 MiB.
+	MaxMemory int64
+
+	// SpoolToDisk writes archives larger than MaxMemory to a temporary file,
+	// removed once extraction ends. Without it they are rejected.
+	SpoolToDisk bool
+
+	// TempDir is where spooled archives are written. Defaults to
+	// os.TempDir.
+	TempDir string
+}
+
+// ExtractZip reads a zip archive from r and passes each file entry, in
+// archive order, to fn. Entries are decompressed as fn reads them and nothing
+// is written to disk unless SpoolToDisk is needed. Returning an error from fn
+// stops extraction.
+func ExtractZip(r io.Reader, opts ArchiveOptions, fn func(name string, entry io.Reader) error) error {
+	maxMemory := opts.MaxMemory
+	if maxMemory <= 0 {
+		maxMemory = 64 << 20
+	}
+
+	var buf bytes.Buffer
+	n, err := io.Copy(&buf, io.LimitReader(r, maxMemory+1))
+	if err != nil {
+		return err
+	}
+
+	if n <= maxMemory {
+		archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), n)
+		if err != nil {
+			return err
+		}
+		return dispatchZipEntries(archive, fn)
+	}
+
+	if !opts.SpoolToDisk {
+		return fmt.Errorf("zip archive exceeds %d bytes and SpoolToDisk is not set", maxMemory)
+	}
+
+	file, err := os.CreateTemp(opts.TempDir, "core-archive-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	size, err := io.Copy(file, io.MultiReader(&buf, r))
+	if err != nil {
+		return err
+	}
+
+	archive, err := zip.NewReader(file, size)
+	if err != nil {
+		return err
+	}
+	return dispatchZipEntries(archive, fn)
+}
+
+// GetExtractZip downloads a zip archive with GetStream and extracts it through
+// ExtractZip.
+func GetExtractZip(
+	ctx context.Context,
+	client Client,
+	path,
+	query string,
+	headersFunc HeaderFunc,
+	opts ArchiveOptions,
+	fn func(name string, entry io.Reader) error,
+) error {
+	res, err := GetStream(ctx, client, path, query, headersFunc)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	return ExtractZip(res.Body, opts, fn)
+}
+
+func dispatchZipEntries(archive *zip.Reader, fn func(name string, entry io.Reader) error) error {
+	for _, f := range archive.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		entry, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("opening %s: %w", f.Name, err)
+		}
+		err = fn(f.Name, entry)
+		entry.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
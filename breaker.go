@@ -0,0 +1,228 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned for calls short-circuited by an open
+// CircuitBreaker.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitState is the state of a CircuitBreaker.
+type CircuitState int
+
+const (
+	CircuitClosed CircuitState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// CircuitBreaker fails calls locally with ErrCircuitOpen once the API has
+// failed repeatedly, instead of adding load to an upstream that is already
+// struggling. After RecoveryTimeout a single trial call is let through; its
+// success closes the circuit and its failure reopens it. Calls admitted
+// before the circuit last changed state do not move it. A call retried by a
+// RetryPolicy counts once. Time is read from the client's Config.Clock.
+type CircuitBreaker struct {
+	// FailureThreshold is the number of consecutive failures, 5xx or
+	// transport errors, that open the circuit. Defaults to 5.
+	FailureThreshold int
+
+	// RecoveryTimeout is how long the circuit stays open before a trial
+	// call. Defaults to 30 seconds.
+	RecoveryTimeout time.Duration
+
+	// OnStateChange, when set, is called on every transition.
+	OnStateChange func(from, to CircuitState)
+
+	mu         sync.Mutex
+	state      CircuitState
+	generation uint64
+	failures   int
+	openedAt   time.Time
+	trial      bool
+}
+
+// admission records how a call was let through, so only the trial call, or
+// a call admitted while closed that ends before the circuit changes state,
+// can move the circuit.
+type admission struct {
+	trial      bool
+	generation uint64
+}
+
+// State returns the current state.
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+func (b *CircuitBreaker) wrap(next CallFunc) CallFunc {
+	if b == nil {
+		return next
+	}
+
+	return func(ctx context.Context, request *ApiRequest, headersFunc HeaderFunc) *ApiResponse {
+		config := configOf(request.Client)
+
+		ticket, allowed := b.allow(config.now())
+		if !allowed {
+			return NewApiErrorResponse(request, &ApiError{
+				Message:   ErrCircuitOpen.Error(),
+				ParsedUrl: request.url(),
+				Err:       ErrCircuitOpen,
+			})
+		}
+
+		response := next(ctx, request, headersFunc)
+		if response.err != nil && CancelReasonOf(response.err) != CancelReasonNone {
+			// The caller gave up; that says nothing about the API.
+			b.release(ticket)
+		} else if isPrimaryFailure(response) {
+			b.recordFailure(ticket, config.now())
+		} else {
+			b.recordSuccess(ticket)
+		}
+		return response
+	}
+}
+
+// allow reports whether a call may proceed, claiming the trial call when one
+// is due.
+func (b *CircuitBreaker) allow(now time.Time) (admission, bool) {
+	b.mu.Lock()
+	from := b.state
+
+	allowed := true
+	ticket := admission{generation: b.generation}
+	switch b.state {
+	case CircuitOpen:
+		if now.Sub(b.openedAt) < b.recoveryTimeout() {
+			allowed = false
+			break
+		}
+		b.setState(CircuitHalfOpen)
+		b.trial = true
+		ticket = admission{trial: true, generation: b.generation}
+	case CircuitHalfOpen:
+		allowed = !b.trial
+		b.trial = true
+		ticket.trial = allowed
+	}
+
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+	return ticket, allowed
+}
+
+// counts reports whether the outcome of the call admitted with ticket may
+// change the circuit. b.mu must be held.
+func (b *CircuitBreaker) counts(ticket admission) bool {
+	if ticket.generation != b.generation {
+		return false
+	}
+	if ticket.trial {
+		return b.state == CircuitHalfOpen
+	}
+	return b.state == CircuitClosed
+}
+
+// release frees the trial slot of a trial call that ended without a verdict.
+func (b *CircuitBreaker) release(ticket admission) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if ticket.trial && b.counts(ticket) {
+		b.trial = false
+	}
+}
+
+func (b *CircuitBreaker) recordSuccess(ticket admission) {
+	b.mu.Lock()
+	from := b.state
+	if b.counts(ticket) {
+		b.failures = 0
+		b.trial = false
+		b.setState(CircuitClosed)
+	}
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+}
+
+func (b *CircuitBreaker) recordFailure(ticket admission, now time.Time) {
+	threshold := b.FailureThreshold
+	if threshold <= 0 {
+		threshold = 5
+	}
+
+	b.mu.Lock()
+	from := b.state
+	if b.counts(ticket) {
+		b.failures++
+		b.trial = false
+		if b.state == CircuitHalfOpen || b.failures >= threshold {
+			b.openedAt = now
+			b.setState(CircuitOpen)
+		}
+	}
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+}
+
+// setState moves to state, starting a new generation on a change. b.mu must
+// be held.
+func (b *CircuitBreaker) setState(state CircuitState) {
+	if state != b.state {
+		b.state = state
+		b.generation++
+	}
+}
+
+func (b *CircuitBreaker) notify(from, to CircuitState) {
+	if from != to && b.OnStateChange != nil {
+		b.OnStateChange(from, to)
+	}
+}
+
+func (b *CircuitBreaker) recoveryTimeout() time.Duration {
+	if b.RecoveryTimeout <= 0 {
+		return 30 * time.Second
+	}
+	return b.RecoveryTimeout
+}
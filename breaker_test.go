@@ -0,0 +1,74 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerIgnoresSuccessAdmittedBeforeOpening(t *testing.T) {
+	b := &CircuitBreaker{FailureThreshold: 1, RecoveryTimeout: time.Minute}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	slow, _ := b.allow(now)
+	failing, _ := b.allow(now)
+	b.recordFailure(failing, now)
+	if b.State() != CircuitOpen {
+		t.Fatalf("state = %v, want open", b.State())
+	}
+
+	b.recordSuccess(slow)
+	if b.State() != CircuitOpen {
+		t.Fatalf("state = %v after a call admitted while closed succeeded, want open", b.State())
+	}
+}
+
+func TestCircuitBreakerOnlyTrialClosesCircuit(t *testing.T) {
+	b := &CircuitBreaker{FailureThreshold: 1, RecoveryTimeout: time.Minute}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	slow, _ := b.allow(now)
+	failing, _ := b.allow(now)
+	b.recordFailure(failing, now)
+
+	now = now.Add(time.Minute)
+	trial, ok := b.allow(now)
+	if !ok || !trial.trial || b.State() != CircuitHalfOpen {
+		t.Fatalf("allow after recovery = %+v, %v, state %v; want a trial in half-open", trial, ok, b.State())
+	}
+	if _, ok := b.allow(now); ok {
+		t.Fatal("second call admitted while the trial is running")
+	}
+
+	b.recordSuccess(slow)
+	if b.State() != CircuitHalfOpen {
+		t.Fatalf("state = %v after a stale success during the trial, want half-open", b.State())
+	}
+
+	b.recordFailure(trial, now)
+	if b.State() != CircuitOpen {
+		t.Fatalf("state = %v after the trial failed, want open", b.State())
+	}
+
+	now = now.Add(time.Minute)
+	trial, _ = b.allow(now)
+	b.recordSuccess(trial)
+	if b.State() != CircuitClosed {
+		t.Fatalf("state = %v after the trial succeeded, want closed", b.State())
+	}
+}
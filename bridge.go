@@ -0,0 +1,79 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// EventSink receives events forwarded from an EventBus to an external bus.
+// Adapters for NATS and Kafka are built with -tags nats and -tags kafka.
+type EventSink interface {
+	Send(ctx context.Context, event Event) error
+}
+
+// EventSinkFunc adapts a function to an EventSink.
+type EventSinkFunc func(ctx context.Context, event Event) error
+
+func (f EventSinkFunc) Send(ctx context.Context, event Event) error {
+	return f(ctx, event)
+}
+
+// ChannelSink returns a sink that sends events on c, blocking until c has
+// room or ctx is done.
+func ChannelSink(c chan<- Event) EventSink {
+	return EventSinkFunc(func(ctx context.Context, event Event) error {
+		select {
+		case c <- event:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// Bridge forwards every event received on sub to sink until ctx is done or
+// sub is closed. Failed sends are passed to onError when it is set; the
+// event is not retried.
+func Bridge(ctx context.Context, sub *Subscription, sink EventSink, onError func(event Event, err error)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if err := sink.Send(ctx, event); err != nil && onError != nil && ctx.Err() == nil {
+				onError(event, err)
+			}
+		}
+	}
+}
+
+// MarshalEvent encodes event as JSON for an external bus, with the payload
+// encoded as is.
+func MarshalEvent(event Event) ([]byte, error) {
+	return json.Marshal(struct {
+		Topic   string      `json:"topic"`
+		Source  string      `json:"source"`
+		Time    time.Time   `json:"time"`
+		Payload interface{} `json:"payload"`
+	}{event.Topic, event.Source, event.Time, event.Payload})
+}
@@ -0,0 +1,40 @@
+//go:build kafka
+
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaSink writes events to the Writer's topic, keyed by event topic so each
+// feed stays ordered within a partition, encoded by MarshalEvent. It is only
+// built with -tags kafka.
+type KafkaSink struct {
+	Writer *kafka.Writer
+}
+
+func (s *KafkaSink) Send(ctx context.Context, event Event) error {
+	data, err := MarshalEvent(event)
+	if err != nil {
+		return err
+	}
+	return s.Writer.WriteMessages(ctx, kafka.Message{Key: []byte(event.Topic), Value: data})
+}
@@ -0,0 +1,145 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+var defaultBufferClasses = []int{4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20}
+
+// DefaultBufferPool uses size classes from 4KiB to 1MiB.
+var DefaultBufferPool = NewBufferPool()
+
+// BufferPoolStats counts BufferPool activity since creation.
+type BufferPoolStats struct {
+	Gets        uint64
+	Puts        uint64
+	Allocations uint64
+	Oversized   uint64
+	Discarded   uint64
+}
+
+// BufferPool is a sync.Pool backed pool of byte buffers grouped into size
+// classes. Buffers that grow beyond the largest class are not retained, which
+// caps the memory a pool can hold on to.
+type BufferPool struct {
+	classes []int
+	pools   []sync.Pool
+
+	gets        atomic.Uint64
+	puts        atomic.Uint64
+	allocations atomic.Uint64
+	oversized   atomic.Uint64
+	discarded   atomic.Uint64
+}
+
+// NewBufferPool returns a pool with the given class sizes in bytes, or the
+// default classes when none are given.
+func NewBufferPool(classSizes ...int) *BufferPool {
+	if len(classSizes) == 0 {
+		classSizes = defaultBufferClasses
+	}
+
+	classes := make([]int, 0, len(classSizes))
+	for _, size := range classSizes {
+		if size > 0 {
+			classes = append(classes, size)
+		}
+	}
+	sort.Ints(classes)
+
+	p := &BufferPool{
+		classes: classes,
+		pools:   make([]sync.Pool, len(classes)),
+	}
+	for i := range p.pools {
+		size := classes[i]
+		p.pools[i].New = func() interface{} {
+			p.allocations.Add(1)
+			return bytes.NewBuffer(make([]byte, 0, size))
+		}
+	}
+	return p
+}
+
+// Get returns an empty buffer with capacity for at least size bytes. Requests
+// larger than the largest class get a new, unpooled buffer.
+func (p *BufferPool) Get(size int) *bytes.Buffer {
+	p.gets.Add(1)
+
+	i := p.class(size)
+	if i < 0 {
+		p.oversized.Add(1)
+		p.allocations.Add(1)
+		return bytes.NewBuffer(make([]byte, 0, size))
+	}
+
+	buf := p.pools[i].Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+// Put returns buf to the pool. buf must not be used afterwards.
+func (p *BufferPool) Put(buf *bytes.Buffer) {
+	if buf == nil {
+		return
+	}
+	p.puts.Add(1)
+
+	// File the buffer under the largest class it can fully serve.
+	capacity := buf.Cap()
+	i := sort.SearchInts(p.classes, capacity+1) - 1
+	if i < 0 || capacity > p.classes[len(p.classes)-1] {
+		p.discarded.Add(1)
+		return
+	}
+
+	buf.Reset()
+	p.pools[i].Put(buf)
+}
+
+func (p *BufferPool) Stats() BufferPoolStats {
+	return BufferPoolStats{
+		Gets:        p.gets.Load(),
+		Puts:        p.puts.Load(),
+		Allocations: p.allocations.Load(),
+		Oversized:   p.oversized.Load(),
+		Discarded:   p.discarded.Load(),
+	}
+}
+
+// largestClass returns the size of the largest class, or 0 when there are
+// none.
+func (p *BufferPool) largestClass() int {
+	if len(p.classes) == 0 {
+		return 0
+	}
+	return p.classes[len(p.classes)-1]
+}
+
+// class returns the index of the smallest class holding size bytes, or -1.
+func (p *BufferPool) class(size int) int {
+	i := sort.SearchInts(p.classes, size)
+	if i == len(p.classes) {
+		return -1
+	}
+	return i
+}
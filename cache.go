@@ -0,0 +1,373 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Cache stores byte values with a time to live, so cached responses can live
+// in process or in a shared store such as Redis. A zero ttl means no expiry.
+// Get reports whether key was found.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+// ResponseCache caches successful GET responses. Add its Middleware to
+// Config.Middleware to enable it.
+//
+// Responses usually depend on the credentials a call is signed with, which
+// the cache cannot see, so a call is only cached when Vary returns a value
+// for it or the cache is marked Public. Other calls pass straight through.
+type ResponseCache struct {
+	// Cache stores the responses. Defaults to an LruCache of
+	// DefaultResponseCacheEntries; set a shared store to share reference
+	// data across processes. Cache errors are treated as misses.
+	Cache Cache
+
+	// Public declares that responses are the same for every caller, as for
+	// public market data, so calls are cached by URL without a Vary value.
+	Public bool
+
+	// Ttl is how long a cached response is served without calling the API.
+	// Zero means every call goes to the API.
+	Ttl time.Duration
+
+	// StaleIfError serves the last successful response, flagged Stale, when
+	// a call fails with a transport error, a 429, or a 5xx.
+	StaleIfError bool
+
+	// LatencyBudget, with StaleIfError, serves the cached response once a
+	// call has taken this long. The call continues in the background and
+	// refreshes the cache if it succeeds. Zero disables the budget.
+	LatencyBudget time.Duration
+
+	// RefreshTimeout bounds a call that continues in the background after
+	// LatencyBudget, since it no longer follows the caller's context.
+	// Defaults to 30 seconds.
+	RefreshTimeout time.Duration
+
+	// MaxStale bounds the age of a response served as stale. Zero means no
+	// bound.
+	MaxStale time.Duration
+
+	// OnStale, when set, is called whenever a stale response is served.
+	OnStale func(request *ApiRequest, age time.Duration)
+
+	// Vary, when set, returns a value added to the cache key, such as the
+	// access key or portfolio a call is made for, so callers whose
+	// responses differ for the same URL do not share entries. Unless the
+	// cache is Public, a call for which it returns "" is not cached. The
+	// Accept header set by WithAccept is always part of the key.
+	Vary func(ctx context.Context, request *ApiRequest) string
+
+	once sync.Once
+}
+
+// DefaultResponseCacheEntries bounds the LruCache a ResponseCache creates
+// when none is set. With StaleIfError and no MaxStale its entries never
+// expire, so the bound is what keeps the cache from growing forever.
+const DefaultResponseCacheEntries = 1024
+
+type cachedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	StoredAt   time.Time   `json:"stored_at"`
+}
+
+// Middleware returns the Middleware that serves and stores cached responses.
+func (c *ResponseCache) Middleware() Middleware {
+	return func(next CallFunc) CallFunc {
+		return func(ctx context.Context, request *ApiRequest, headersFunc HeaderFunc) *ApiResponse {
+			if request.HttpMethod != http.MethodGet {
+				return next(ctx, request, headersFunc)
+			}
+
+			key, ok := c.key(ctx, request)
+			if !ok {
+				return next(ctx, request, headersFunc)
+			}
+			entry := c.get(ctx, key)
+
+			if entry != nil && c.Ttl > 0 && time.Since(entry.StoredAt) < c.Ttl {
+				return entry.serve(request, false)
+			}
+
+			response := c.fetch(ctx, key, request, headersFunc, next, entry)
+
+			if response.err != nil && c.StaleIfError && isStaleEligible(response.err) && c.servable(entry) {
+				return c.serveStale(request, entry)
+			}
+
+			return response
+		}
+	}
+}
+
+// Purge removes every cached response when the Cache supports it, as
+// LruCache does.
+func (c *ResponseCache) Purge() {
+	if purger, ok := c.cache().(interface{ Purge() }); ok {
+		purger.Purge()
+	}
+}
+
+func (c *ResponseCache) cache() Cache {
+	c.once.Do(func() {
+		if c.Cache == nil {
+			c.Cache = NewLruCache(DefaultResponseCacheEntries)
+		}
+	})
+	return c.Cache
+}
+
+func (c *ResponseCache) fetch(
+	ctx context.Context,
+	key string,
+	request *ApiRequest,
+	headersFunc HeaderFunc,
+	next CallFunc,
+	entry *cachedResponse,
+) *ApiResponse {
+	if !c.StaleIfError || c.LatencyBudget <= 0 || !c.servable(entry) {
+		return c.store(ctx, key, next(ctx, request, headersFunc))
+	}
+
+	// The call may outlive the caller, so it must not be canceled with it.
+	refreshCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.refreshTimeout())
+	result := make(chan *ApiResponse, 1)
+	go func() {
+		defer cancel()
+		result <- c.store(refreshCtx, key, next(refreshCtx, request, headersFunc))
+	}()
+
+	timer := time.NewTimer(c.LatencyBudget)
+	defer timer.Stop()
+
+	select {
+	case response := <-result:
+		return response
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+	if c.servable(entry) {
+		return c.serveStale(request, entry)
+	}
+
+	select {
+	case response := <-result:
+		return response
+	case <-ctx.Done():
+		err := classifyError(ctx, ctx.Err())
+		return NewApiErrorResponse(request, &ApiError{
+			Message:   err.Error(),
+			ParsedUrl: request.url(),
+			Err:       err,
+		})
+	}
+}
+
+func (c *ResponseCache) refreshTimeout() time.Duration {
+	if c.RefreshTimeout <= 0 {
+		return 30 * time.Second
+	}
+	return c.RefreshTimeout
+}
+
+func (c *ResponseCache) serveStale(request *ApiRequest, entry *cachedResponse) *ApiResponse {
+	if c.OnStale != nil {
+		c.OnStale(request, time.Since(entry.StoredAt))
+	}
+	return entry.serve(request, true)
+}
+
+func (c *ResponseCache) servable(entry *cachedResponse) bool {
+	return entry != nil && (c.MaxStale <= 0 || time.Since(entry.StoredAt) <= c.MaxStale)
+}
+
+func (c *ResponseCache) get(ctx context.Context, key string) *cachedResponse {
+	value, ok, err := c.cache().Get(ctx, key)
+	if err != nil || !ok {
+		return nil
+	}
+	entry := &cachedResponse{}
+	if err := json.Unmarshal(value, entry); err != nil {
+		return nil
+	}
+	return entry
+}
+
+func (c *ResponseCache) store(ctx context.Context, key string, response *ApiResponse) *ApiResponse {
+	if response.err != nil {
+		return response
+	}
+
+	// Entries are kept as long as they may be served, fresh or stale.
+	ttl := c.Ttl
+	switch {
+	case c.StaleIfError && c.MaxStale <= 0:
+		ttl = 0
+	case c.StaleIfError && c.MaxStale > ttl:
+		ttl = c.MaxStale
+	case ttl <= 0:
+		return response
+	}
+
+	value, err := json.Marshal(&cachedResponse{
+		StatusCode: response.statusCode,
+		Header:     response.header,
+		Body:       response.body,
+		StoredAt:   time.Now(),
+	})
+	if err == nil {
+		c.cache().Set(ctx, key, value, ttl)
+	}
+
+	return response
+}
+
+func (e *cachedResponse) serve(request *ApiRequest, stale bool) *ApiResponse {
+	served := NewApiResponse(request, e.StatusCode, e.Header, e.Body)
+	served.stale = stale
+	return served
+}
+
+// key identifies the response to request, and reports false when the call
+// must not be cached because nothing tells its caller apart. A call with no
+// Accept or Vary value is keyed by its URL alone.
+func (c *ResponseCache) key(ctx context.Context, request *ApiRequest) (string, bool) {
+	key := request.url()
+	if accept := AcceptFromContext(ctx); accept != "" {
+		key += "\naccept=" + accept
+	}
+
+	var vary string
+	if c.Vary != nil {
+		vary = c.Vary(ctx, request)
+	}
+	if vary == "" {
+		return key, c.Public
+	}
+	return key + "\nvary=" + vary, true
+}
+
+func isStaleEligible(err *ApiError) bool {
+	return err.CodeReceived == 0 ||
+		err.CodeReceived == http.StatusTooManyRequests ||
+		err.CodeReceived >= http.StatusInternalServerError
+}
+
+// LruCache is an in-memory Cache holding at most maxEntries values, dropping
+// the least recently used first.
+type LruCache struct {
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewLruCache returns a cache of at most maxEntries values. Zero means no
+// limit.
+func NewLruCache(maxEntries int) *LruCache {
+	return &LruCache{maxEntries: maxEntries, entries: map[string]*list.Element{}, lru: list.New()}
+}
+
+func (c *LruCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := element.Value.(*lruEntry)
+	if !entry.expiresAt.IsZero() && !time.Now().Before(entry.expiresAt) {
+		c.remove(element)
+		return nil, false, nil
+	}
+	c.lru.MoveToFront(element)
+	return entry.value, true, nil
+}
+
+func (c *LruCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &lruEntry{key: key, value: bytes.Clone(value)}
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.lru.MoveToFront(element)
+		return nil
+	}
+
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.maxEntries > 0 && c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+	}
+	return nil
+}
+
+func (c *LruCache) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+	return nil
+}
+
+// Purge removes every value.
+func (c *LruCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[string]*list.Element{}
+	c.lru.Init()
+}
+
+// Len returns the number of values held, including expired ones not yet
+// dropped.
+func (c *LruCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// remove drops element. c.mu must be held.
+func (c *LruCache) remove(element *list.Element) {
+	c.lru.Remove(element)
+	delete(c.entries, element.Value.(*lruEntry).key)
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type testAccessKey struct{}
+
+func TestResponseCacheKeyVariesByAcceptAndVary(t *testing.T) {
+	cache := &ResponseCache{Public: true, Vary: func(ctx context.Context, request *ApiRequest) string {
+		key, _ := ctx.Value(testAccessKey{}).(string)
+		return key
+	}}
+	request := &ApiRequest{Path: "/portfolios", HttpMethod: http.MethodGet, Client: NewClient("https://api.example.com", nil)}
+	ctx := context.Background()
+
+	keys := map[string]string{}
+	for name, ctx := range map[string]context.Context{
+		"plain":     ctx,
+		"csv":       WithAccept(ctx, AcceptCsv),
+		"other key": context.WithValue(ctx, testAccessKey{}, "other"),
+	} {
+		key, _ := cache.key(ctx, request)
+		if previous, ok := keys[key]; ok {
+			t.Fatalf("%s and %s share cache key %q", previous, name, key)
+		}
+		keys[key] = name
+	}
+
+	if key, _ := cache.key(ctx, request); key != request.url() {
+		t.Fatalf("key without Accept or Vary = %q, want the URL %q", key, request.url())
+	}
+}
+
+func TestResponseCacheSkipsCallsWithoutDiscriminator(t *testing.T) {
+	cache := &ResponseCache{Ttl: time.Hour, Vary: func(ctx context.Context, request *ApiRequest) string {
+		key, _ := ctx.Value(testAccessKey{}).(string)
+		return key
+	}}
+	request := &ApiRequest{Path: "/accounts", HttpMethod: http.MethodGet, Client: NewClient("https://api.example.com", nil)}
+
+	calls := 0
+	next := func(ctx context.Context, request *ApiRequest, headersFunc HeaderFunc) *ApiResponse {
+		calls++
+		return NewApiResponse(request, http.StatusOK, nil, []byte(`{}`))
+	}
+	get := cache.Middleware()(next)
+
+	for i := 0; i < 2; i++ {
+		get(context.Background(), request, nil)
+	}
+	if calls != 2 {
+		t.Fatalf("calls without a Vary value reached the API %d times, want 2 since they are not cached", calls)
+	}
+
+	signed := context.WithValue(context.Background(), testAccessKey{}, "key-a")
+	for i := 0; i < 2; i++ {
+		get(signed, request, nil)
+	}
+	if calls != 3 {
+		t.Fatalf("calls with a Vary value reached the API %d times in total, want 3", calls)
+	}
+}
+
+func TestResponseCacheDefaultStoreIsBounded(t *testing.T) {
+	cache := &ResponseCache{Public: true, StaleIfError: true}
+	for i := 0; i <= DefaultResponseCacheEntries; i++ {
+		request := &ApiRequest{Path: "/products/" + strconv.Itoa(i), HttpMethod: http.MethodGet, Client: NewClient("https://api.example.com", nil)}
+		key, _ := cache.key(context.Background(), request)
+		cache.store(context.Background(), key, NewApiResponse(request, http.StatusOK, nil, []byte(`{}`)))
+	}
+
+	if n := cache.cache().(*LruCache).Len(); n != DefaultResponseCacheEntries {
+		t.Fatalf("default cache holds %d entries, want %d", n, DefaultResponseCacheEntries)
+	}
+}
+
+func TestResponseCacheRefreshOutlivesCaller(t *testing.T) {
+	cache := &ResponseCache{Public: true, StaleIfError: true, LatencyBudget: time.Millisecond}
+	request := &ApiRequest{Path: "/products", HttpMethod: http.MethodGet, Client: NewClient("https://api.example.com", nil)}
+	key, _ := cache.key(context.Background(), request)
+	cache.store(context.Background(), key, NewApiResponse(request, http.StatusOK, nil, []byte(`"old"`)))
+
+	release := make(chan struct{})
+	refreshed := make(chan error, 1)
+	next := func(ctx context.Context, request *ApiRequest, headersFunc HeaderFunc) *ApiResponse {
+		<-release
+		refreshed <- ctx.Err()
+		return NewApiResponse(request, http.StatusOK, nil, []byte(`"new"`))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	response := cache.Middleware()(next)(ctx, request, nil)
+	if !response.Stale() || string(response.Body()) != `"old"` {
+		t.Fatalf("response = %s (stale %v), want the stale cached body", response.Body(), response.Stale())
+	}
+
+	cancel()
+	close(release)
+	if err := <-refreshed; err != nil {
+		t.Fatalf("background refresh saw %v after the caller returned", err)
+	}
+	for deadline := time.Now().Add(time.Second); ; {
+		if entry := cache.get(context.Background(), key); entry != nil && string(entry.Body) == `"new"` {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("background refresh did not update the cache")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrClientShutdown is the cause of calls aborted by CancelAll.
+var ErrClientShutdown = errors.New("client shut down")
+
+// CancelReason says why a call was aborted before it completed.
+type CancelReason int
+
+const (
+	CancelReasonNone CancelReason = iota
+	CancelReasonCanceled
+	CancelReasonDeadlineExceeded
+	CancelReasonShutdown
+	CancelReasonKillSwitch
+)
+
+func (r CancelReason) String() string {
+	switch r {
+	case CancelReasonCanceled:
+		return "canceled"
+	case CancelReasonDeadlineExceeded:
+		return "deadline exceeded"
+	case CancelReasonShutdown:
+		return "client shutdown"
+	case CancelReasonKillSwitch:
+		return "kill switch"
+	default:
+		return "none"
+	}
+}
+
+// CancellationError is set as ApiError.Err for aborted calls. It matches both
+// the context error, such as context.Canceled, and the cause, such as
+// ErrClientShutdown or ErrKillSwitchTripped, with errors.Is.
+type CancellationError struct {
+	Reason CancelReason
+	Err    error
+	Cause  error
+}
+
+func (e *CancellationError) Error() string {
+	if e.Cause != nil && e.Cause != e.Err {
+		return fmt.Sprintf("call aborted (%s): %v", e.Reason, e.Cause)
+	}
+	return fmt.Sprintf("call aborted (%s): %v", e.Reason, e.Err)
+}
+
+func (e *CancellationError) Unwrap() []error {
+	var errs []error
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	if e.Cause != nil && e.Cause != e.Err {
+		errs = append(errs, e.Cause)
+	}
+	return errs
+}
+
+// CancelReasonOf returns why err's call was aborted, or CancelReasonNone.
+func CancelReasonOf(err error) CancelReason {
+	var cancellation *CancellationError
+	if errors.As(err, &cancellation) {
+		return cancellation.Reason
+	}
+	return CancelReasonNone
+}
+
+// classifyError wraps err in a CancellationError when it was caused by ctx
+// ending or the kill switch, and returns it unchanged otherwise.
+func classifyError(ctx context.Context, err error) error {
+	if errors.Is(err, ErrKillSwitchTripped) {
+		return &CancellationError{Reason: CancelReasonKillSwitch, Cause: err}
+	}
+
+	ctxErr := ctx.Err()
+	if ctxErr == nil {
+		return err
+	}
+
+	cause := context.Cause(ctx)
+	switch {
+	case errors.Is(cause, ErrClientShutdown):
+		return &CancellationError{Reason: CancelReasonShutdown, Err: ctxErr, Cause: cause}
+	case errors.Is(ctxErr, context.DeadlineExceeded):
+		return &CancellationError{Reason: CancelReasonDeadlineExceeded, Err: ctxErr, Cause: cause}
+	default:
+		return &CancellationError{Reason: CancelReasonCanceled, Err: ctxErr, Cause: cause}
+	}
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"net/http"
+	"sync"
+)
+
+// RestClient is the standard Client implementation, so SDKs need not define
+// their own. It carries a Config, which starts empty.
+type RestClient struct {
+	baseUrl    string
+	httpClient *http.Client
+	config     *Config
+
+	debugOnce sync.Once
+	debug     *DebugTransport
+}
+
+// NewClient returns a client for baseUrl, e.g. "https://api.coinbase.com".
+// A nil httpClient uses a new http.Client with the default transport.
+func NewClient(baseUrl string, httpClient *http.Client) *RestClient {
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+	return &RestClient{baseUrl: baseUrl, httpClient: httpClient, config: &Config{}}
+}
+
+func (c *RestClient) HttpBaseUrl() string {
+	return c.baseUrl
+}
+
+func (c *RestClient) HttpClient() *http.Client {
+	return c.httpClient
+}
+
+func (c *RestClient) Config() *Config {
+	return c.config
+}
+
+// Use appends middleware to the client's chain. See Config.Use.
+func (c *RestClient) Use(middleware ...Middleware) {
+	c.config.Use(middleware...)
+}
+
+// SetDebug turns dumping of every request and response on or off, through
+// a DebugTransport logging to Config.Logger, or slog.Default(), at Debug
+// level. The first call installs the transport in a copy of the client's
+// http.Client, so make it before the client is shared; later calls may be
+// made at any time.
+func (c *RestClient) SetDebug(enabled bool) {
+	c.debugOnce.Do(func() {
+		next := c.httpClient.Transport
+		if next == nil {
+			next = http.DefaultTransport
+		}
+		c.debug = &DebugTransport{Next: next, Logger: c.config.Logger}
+
+		httpClient := *c.httpClient
+		httpClient.Transport = c.debug
+		c.httpClient = &httpClient
+	})
+	c.debug.SetEnabled(enabled)
+}
@@ -0,0 +1,205 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"container/list"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ClientFactory builds the Client for key. transport is shared by every
+// client in the pool; wrap it rather than replacing it to keep connection
+// reuse across tenants.
+type ClientFactory func(key string, transport http.RoundTripper) (Client, error)
+
+// ClientPool lazily builds and caches one Client per tenant or set of
+// credentials, for platforms calling the API on behalf of many customers.
+// The least recently used client is dropped once MaxSize is reached, and
+// clients unused for IdleTimeout are dropped on the next Get.
+//
+// Clients share one transport. This is safe as long as credentials are only
+// applied in HeaderFuncs and not through TLS client certificates.
+type ClientPool struct {
+	maxSize     int
+	idleTimeout time.Duration
+	factory     ClientFactory
+	transport   http.RoundTripper
+
+	mu       sync.Mutex
+	entries  map[string]*list.Element
+	lru      *list.List
+	building map[string]*pendingClient
+}
+
+// pendingClient is a client being built by the factory. done is closed once
+// client and err are set.
+type pendingClient struct {
+	done   chan struct{}
+	client Client
+	err    error
+}
+
+type pooledClient struct {
+	key      string
+	client   Client
+	lastUsed time.Time
+}
+
+// NewClientPool returns a pool building clients with factory. A maxSize or
+// idleTimeout of zero means no limit. transport defaults to a clone of
+// http.DefaultTransport.
+func NewClientPool(maxSize int, idleTimeout time.Duration, transport http.RoundTripper, factory ClientFactory) *ClientPool {
+	if transport == nil {
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+	return &ClientPool{
+		maxSize:     maxSize,
+		idleTimeout: idleTimeout,
+		factory:     factory,
+		transport:   transport,
+		entries:     map[string]*list.Element{},
+		lru:         list.New(),
+		building:    map[string]*pendingClient{},
+	}
+}
+
+// Get returns the client for key, building it on first use. The factory is
+// called without holding the pool's lock, so a slow build only delays
+// callers asking for the same key, who share its result.
+func (p *ClientPool) Get(key string) (Client, error) {
+	p.mu.Lock()
+	now := time.Now()
+	p.evictIdle(now)
+
+	if e, ok := p.entries[key]; ok {
+		entry := e.Value.(*pooledClient)
+		entry.lastUsed = now
+		p.lru.MoveToFront(e)
+		p.mu.Unlock()
+		return entry.client, nil
+	}
+
+	if pending, ok := p.building[key]; ok {
+		p.mu.Unlock()
+		<-pending.done
+		return pending.client, pending.err
+	}
+
+	pending := &pendingClient{done: make(chan struct{})}
+	p.building[key] = pending
+	p.mu.Unlock()
+
+	pending.client, pending.err = p.factory(key, p.transport)
+
+	// A Remove while building drops the result, since the client may have
+	// been built from revoked credentials.
+	p.mu.Lock()
+	current := p.building[key] == pending
+	if current {
+		delete(p.building, key)
+	}
+	if pending.err == nil && current {
+		p.entries[key] = p.lru.PushFront(&pooledClient{key: key, client: pending.client, lastUsed: time.Now()})
+		for p.maxSize > 0 && p.lru.Len() > p.maxSize {
+			p.remove(p.lru.Back())
+		}
+	}
+	p.mu.Unlock()
+
+	close(pending.done)
+	return pending.client, pending.err
+}
+
+// Remove drops the client for key, e.g. after its credentials are revoked.
+func (p *ClientPool) Remove(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if e, ok := p.entries[key]; ok {
+		p.remove(e)
+	}
+	delete(p.building, key)
+}
+
+// EvictIdle drops clients unused for the idle timeout and returns how many
+// were dropped.
+func (p *ClientPool) EvictIdle() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.evictIdle(time.Now())
+}
+
+// Len returns the number of pooled clients.
+func (p *ClientPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lru.Len()
+}
+
+func (p *ClientPool) evictIdle(now time.Time) int {
+	if p.idleTimeout <= 0 {
+		return 0
+	}
+
+	evicted := 0
+	for e := p.lru.Back(); e != nil; e = p.lru.Back() {
+		if now.Sub(e.Value.(*pooledClient).lastUsed) < p.idleTimeout {
+			break
+		}
+		p.remove(e)
+		evicted++
+	}
+	return evicted
+}
+
+func (p *ClientPool) remove(e *list.Element) {
+	delete(p.entries, e.Value.(*pooledClient).key)
+	p.lru.Remove(e)
+}
+
+var (
+	credentialsKeySecretOnce sync.Once
+	credentialsKeySecret     []byte
+)
+
+// CredentialsKey returns a pool key for credentials that does not contain the
+// secrets themselves. Rotated credentials get a new key. Keys are an HMAC
+// under a random per-process secret, so a key that leaks, e.g. into logs or
+// metrics labels, cannot be used to test guesses of the signing key; they
+// are therefore not stable across processes.
+func CredentialsKey(credentials *Credentials) string {
+	credentialsKeySecretOnce.Do(func() {
+		credentialsKeySecret = make([]byte, 32)
+		if _, err := rand.Read(credentialsKeySecret); err != nil {
+			panic("core: reading random credentials key secret: " + err.Error())
+		}
+	})
+
+	h := hmac.New(sha256.New, credentialsKeySecret)
+	h.Write([]byte(credentials.AccessKey))
+	h.Write([]byte{0})
+	h.Write([]byte(credentials.Passphrase))
+	h.Write([]byte{0})
+	h.Write([]byte(credentials.SigningKey))
+	return hex.EncodeToString(h.Sum(nil))
+}
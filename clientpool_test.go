@@ -0,0 +1,89 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientPoolBuildsOutsideLock(t *testing.T) {
+	release := make(chan struct{})
+	var builds atomic.Int32
+	pool := NewClientPool(0, 0, nil, func(key string, transport http.RoundTripper) (Client, error) {
+		builds.Add(1)
+		if key == "slow" {
+			<-release
+		}
+		return NewClient("https://api.example.com", &http.Client{Transport: transport}), nil
+	})
+
+	var wg sync.WaitGroup
+	clients := make([]Client, 2)
+	for i := range clients {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			clients[i], _ = pool.Get("slow")
+		}(i)
+	}
+
+	fast := make(chan error, 1)
+	go func() {
+		_, err := pool.Get("fast")
+		fast <- err
+	}()
+	select {
+	case err := <-fast:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Get for another key waited on a slow build")
+	}
+
+	close(release)
+	wg.Wait()
+	if clients[0] == nil || clients[0] != clients[1] {
+		t.Fatal("concurrent Gets for one key returned different clients")
+	}
+	if n := builds.Load(); n != 2 {
+		t.Fatalf("factory called %d times, want once per key", n)
+	}
+}
+
+func TestCredentialsKeyIsKeyed(t *testing.T) {
+	credentials := &Credentials{AccessKey: "access-key", Passphrase: "passphrase", SigningKey: testHmacSecret}
+
+	key := CredentialsKey(credentials)
+	if key != CredentialsKey(&Credentials{AccessKey: "access-key", Passphrase: "passphrase", SigningKey: testHmacSecret}) {
+		t.Fatal("CredentialsKey differs for equal credentials")
+	}
+	if key == CredentialsKey(&Credentials{AccessKey: "access-key", Passphrase: "passphrase", SigningKey: "rotated"}) {
+		t.Fatal("CredentialsKey unchanged after the signing key rotated")
+	}
+
+	unkeyed := sha256.Sum256([]byte("access-key\x00passphrase\x00" + testHmacSecret))
+	if key == hex.EncodeToString(unkeyed[:]) {
+		t.Fatal("CredentialsKey is a plain hash of the credentials")
+	}
+}
@@ -0,0 +1,124 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"time"
+)
+
+// Clock supplies the time used to sign requests.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+// SystemClock is the local wall clock.
+var SystemClock Clock = systemClock{}
+
+// Ticker is the subset of time.Ticker used by periodic components.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// TickerClock is a Clock that can also create tickers, which lets periodic
+// components such as SessionKeeper run against a fake clock in tests.
+type TickerClock interface {
+	Clock
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer is the subset of time.Timer used by waits.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// TimerClock is a Clock that can also create timers, which lets retries and
+// backoff run against a fake clock in tests.
+type TimerClock interface {
+	Clock
+	NewTimer(d time.Duration) Timer
+}
+
+// sleepClock waits for d on clock, using its timers when it provides them,
+// and reports false if ctx ended first. A non-positive d does not wait, so a
+// fake clock need not be advanced for it.
+func sleepClock(ctx context.Context, clock Clock, d time.Duration) bool {
+	if _, ok := clock.(TimerClock); !ok {
+		return sleepContext(ctx, d)
+	}
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+
+	timer := newTimer(clock, d)
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+		return false
+	case <-timer.C():
+		return true
+	}
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+// newTimer uses clock's timers when it provides them and real ones otherwise.
+func newTimer(clock Clock, d time.Duration) Timer {
+	if tc, ok := clock.(TimerClock); ok {
+		return tc.NewTimer(d)
+	}
+	return SystemClock.(TimerClock).NewTimer(d)
+}
+
+// newTicker uses clock's tickers when it provides them and real ones
+// otherwise.
+func newTicker(clock Clock, d time.Duration) Ticker {
+	if tc, ok := clock.(TickerClock); ok {
+		return tc.NewTicker(d)
+	}
+	return SystemClock.(TickerClock).NewTicker(d)
+}
@@ -0,0 +1,175 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coinbase-samples/core-go"
+	"github.com/coinbase-samples/core-go/testutil"
+)
+
+func noHeaders(*http.Request, string, []byte, core.Client, time.Time) {}
+
+// failingServer answers the first failures requests with 503 and the rest
+// with 200.
+func failingServer(t *testing.T, failures int32) *httptest.Server {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRetryWaitsOnClock(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Time{})
+	client := core.NewClient(failingServer(t, 2).URL, nil)
+	client.Config().Clock = clock
+	client.Config().Retry = &core.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Hour}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- core.HttpGet(context.Background(), client, "/", core.EmptyQueryParams, nil, nil, noHeaders)
+	}()
+
+	for _, wait := range []time.Duration{time.Minute, 2 * time.Minute} {
+		clock.BlockUntil(1)
+		select {
+		case err := <-done:
+			t.Fatalf("call finished before the clock advanced: %v", err)
+		default:
+		}
+		clock.Advance(wait)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("call failed after retries: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("call did not finish after the clock advanced")
+	}
+}
+
+func TestCanceledWaitStopsItsTimer(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Time{})
+	g := core.NewReconnectGovernor(time.Hour, time.Hour, 0)
+	g.Clock = clock
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- g.Wait(ctx, 5) }()
+
+	clock.BlockUntil(1)
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Wait = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Wait did not return after cancel")
+	}
+
+	if pending := clock.Pending(); pending != 0 {
+		t.Fatalf("%d timers pending after the wait was canceled, want 0", pending)
+	}
+}
+
+func TestStalenessDetectorWatchesOnClock(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Time{})
+	d := core.NewStalenessDetector(map[string]time.Duration{"ticker": 5 * time.Second})
+	d.Clock = clock
+	d.Observe("ticker", "BTC-USD")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := d.Watch(ctx, 6*time.Second)
+
+	clock.BlockUntil(1)
+	clock.Advance(6 * time.Second)
+	select {
+	case event := <-events:
+		if event.ProductId != "BTC-USD" || event.Gap != 6*time.Second {
+			t.Fatalf("event = %+v, want BTC-USD stale for 6s", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no staleness event after the clock passed the SLA")
+	}
+}
+
+func TestReconnectGovernorWaitsOnClock(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Time{})
+	g := core.NewReconnectGovernor(0, 0, 1)
+	g.Clock = clock
+
+	if err := g.Wait(context.Background(), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- g.Wait(context.Background(), 0) }()
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("second reconnect did not start once its slot came up")
+	}
+}
+
+func TestCircuitBreakerRecoversOnClock(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Time{})
+	client := core.NewClient(failingServer(t, 1).URL, nil)
+	client.Config().Clock = clock
+	client.Config().CircuitBreaker = &core.CircuitBreaker{FailureThreshold: 1, RecoveryTimeout: 30 * time.Second}
+	get := func() error {
+		return core.HttpGet(context.Background(), client, "/", core.EmptyQueryParams, nil, nil, noHeaders)
+	}
+
+	if err := get(); err == nil {
+		t.Fatal("first call succeeded, want 503")
+	}
+	clock.Advance(29 * time.Second)
+	if err := get(); !errors.Is(err, core.ErrCircuitOpen) {
+		t.Fatalf("call before RecoveryTimeout = %v, want ErrCircuitOpen", err)
+	}
+
+	clock.Advance(time.Second)
+	if err := get(); err != nil {
+		t.Fatalf("trial call = %v, want success", err)
+	}
+	if state := client.Config().CircuitBreaker.State(); state != core.CircuitClosed {
+		t.Fatalf("state = %v after the trial succeeded, want closed", state)
+	}
+}
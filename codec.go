@@ -0,0 +1,105 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strings"
+	"sync"
+)
+
+// Codec decodes response bodies of the media types it declares.
+type Codec interface {
+	MediaTypes() []string
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[string]Codec{}
+)
+
+func init() {
+	RegisterCodec(JsonCodec{})
+	RegisterCodec(&ProtobufCodec{})
+}
+
+// RegisterCodec makes codec the decoder for its media types, replacing any
+// codec registered for them before.
+func RegisterCodec(codec Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	for _, mediaType := range codec.MediaTypes() {
+		codecs[strings.ToLower(mediaType)] = codec
+	}
+}
+
+// codecFor returns the codec for a Content-Type header, falling back to JSON
+// when the type is missing or unregistered.
+func codecFor(contentType string) Codec {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil {
+		codecsMu.RLock()
+		codec, ok := codecs[mediaType]
+		codecsMu.RUnlock()
+		if ok {
+			return codec
+		}
+	}
+	return JsonCodec{}
+}
+
+// JsonCodec decodes application/json, the default for every response.
+type JsonCodec struct{}
+
+func (JsonCodec) MediaTypes() []string {
+	return []string{"application/json"}
+}
+
+func (JsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// ProtobufUnmarshaler is implemented by generated messages that decode
+// themselves, such as those from gogoproto.
+type ProtobufUnmarshaler interface {
+	Unmarshal(data []byte) error
+}
+
+// ProtobufCodec decodes application/protobuf and application/x-protobuf. By
+// default the target must implement ProtobufUnmarshaler; set Func, e.g. to a
+// wrapper around proto.Unmarshal, to decode other messages without core
+// depending on a protobuf library.
+type ProtobufCodec struct {
+	Func func(data []byte, v interface{}) error
+}
+
+func (*ProtobufCodec) MediaTypes() []string {
+	return []string{"application/protobuf", "application/x-protobuf"}
+}
+
+func (c *ProtobufCodec) Unmarshal(data []byte, v interface{}) error {
+	if c.Func != nil {
+		return c.Func(data, v)
+	}
+	if m, ok := v.(ProtobufUnmarshaler); ok {
+		return m.Unmarshal(data)
+	}
+	return fmt.Errorf("cannot decode protobuf into %T: it does not implement ProtobufUnmarshaler and ProtobufCodec.Func is not set", v)
+}
@@ -0,0 +1,153 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// RequestCompression gzips request bodies of at least MinSize bytes sent to
+// hosts that support it. A host is taken to support it once one of its
+// responses lists gzip in Accept-Encoding (RFC 7694), and not to once it
+// answers a compressed request with 415, which is then sent again
+// uncompressed. Bodies are signed before they are compressed.
+type RequestCompression struct {
+	// MinSize is the smallest body compressed. Defaults to 1024 bytes.
+	MinSize int
+
+	// Level is the gzip level. Defaults to gzip.DefaultCompression.
+	Level int
+
+	// Assume compresses for hosts not yet heard from, rather than waiting
+	// for them to advertise support.
+	Assume bool
+
+	mu    sync.Mutex
+	hosts map[string]bool
+}
+
+// Supported reports whether bodies sent to host are compressed.
+func (c *RequestCompression) Supported(host string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	supported, ok := c.hosts[host]
+	if !ok {
+		return c.Assume
+	}
+	return supported
+}
+
+// compress replaces the body of req with its gzip encoding when it is large
+// enough and the host supports it, reporting both sizes to hooks, and reports
+// whether it did.
+func (c *RequestCompression) compress(ctx context.Context, req *http.Request, body []byte, request *ApiRequest, hooks *Hooks) (bool, error) {
+	if c == nil || len(body) < c.minSize() || !c.Supported(req.URL.Host) {
+		return false, nil
+	}
+
+	level := c.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, level)
+	if err != nil {
+		return false, err
+	}
+	if _, err := w.Write(body); err != nil {
+		return false, err
+	}
+	if err := w.Close(); err != nil {
+		return false, err
+	}
+
+	compressed := buf.Bytes()
+	req.Body = io.NopCloser(bytes.NewReader(compressed))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(compressed)), nil
+	}
+	req.ContentLength = int64(len(compressed))
+	req.Header.Set("Content-Encoding", "gzip")
+
+	if hooks != nil && hooks.RequestCompressed != nil {
+		hooks.RequestCompressed(ctx, request, len(body), len(compressed))
+	}
+	return true, nil
+}
+
+// decompressBody puts body, uncompressed, back on a request compress changed.
+func decompressBody(req *http.Request, body []byte) {
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	req.ContentLength = int64(len(body))
+	req.Header.Del("Content-Encoding")
+}
+
+// observe records whether the host of req supports compressed bodies, as
+// shown by res.
+func (c *RequestCompression) observe(req *http.Request, res *http.Response) {
+	if c == nil {
+		return
+	}
+
+	var supported, known bool
+	switch {
+	case res.StatusCode == http.StatusUnsupportedMediaType && req.Header.Get("Content-Encoding") == "gzip":
+		supported, known = false, true
+	case acceptsGzip(res.Header.Values("Accept-Encoding")):
+		supported, known = true, true
+	}
+	if !known {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.hosts == nil {
+		c.hosts = map[string]bool{}
+	}
+	c.hosts[req.URL.Host] = supported
+}
+
+func (c *RequestCompression) minSize() int {
+	if c.MinSize <= 0 {
+		return 1024
+	}
+	return c.MinSize
+}
+
+func acceptsGzip(values []string) bool {
+	for _, value := range values {
+		for _, coding := range strings.Split(value, ",") {
+			name, params, _ := strings.Cut(strings.TrimSpace(coding), ";")
+			if strings.EqualFold(strings.TrimSpace(name), "gzip") && strings.ReplaceAll(params, " ", "") != "q=0" {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRequestCompressionResendsUncompressedAfter415(t *testing.T) {
+	var encodings []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encodings = append(encodings, r.Header.Get("Content-Encoding"))
+		if r.Header.Get("Content-Encoding") != "" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		io.Copy(w, r.Body)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, nil)
+	client.Config().RequestCompression = &RequestCompression{MinSize: 1, Assume: true}
+	client.Config().GuardSignedRequests = true
+
+	body := map[string]string{"note": strings.Repeat("x", 64)}
+	var echoed map[string]string
+	if err := HttpPost(context.Background(), client, "/orders", EmptyQueryParams, body, &echoed, noHeaders); err != nil {
+		t.Fatal(err)
+	}
+	if echoed["note"] != body["note"] {
+		t.Fatalf("server received %v, want %v", echoed, body)
+	}
+	if len(encodings) != 2 || encodings[0] != "gzip" || encodings[1] != "" {
+		t.Fatalf("Content-Encoding per attempt = %q, want gzip then none", encodings)
+	}
+
+	host, _ := url.Parse(server.URL)
+	if client.Config().RequestCompression.Supported(host.Host) {
+		t.Fatal("host still marked as supporting compression after a 415")
+	}
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// checksumHeaders are checked in order by Checksum.
+var checksumHeaders = []string{"Content-Digest", "Digest", "Content-MD5", "X-Checksum-Sha256"}
+
+// ETag returns the response's entity tag, or "".
+func (r *ApiResponse) ETag() string {
+	return r.header.Get("ETag")
+}
+
+// LastModified returns the response's Last-Modified time, if it has one.
+func (r *ApiResponse) LastModified() (time.Time, bool) {
+	value := r.header.Get("Last-Modified")
+	if value == "" {
+		return time.Time{}, false
+	}
+	t, err := http.ParseTime(value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// Checksum returns the first content checksum header present on the response,
+// along with its name.
+func (r *ApiResponse) Checksum() (header, value string) {
+	for _, name := range checksumHeaders {
+		if value := r.header.Get(name); value != "" {
+			return name, value
+		}
+	}
+	return "", ""
+}
+
+// Validators holds what a conditional request needs from a prior response.
+// It can be kept by a cache in place of the response itself.
+type Validators struct {
+	ETag         string
+	LastModified time.Time
+}
+
+// ValidatorsOf returns the validators of r.
+func ValidatorsOf(r *ApiResponse) Validators {
+	lastModified, _ := r.LastModified()
+	return Validators{ETag: r.ETag(), LastModified: lastModified}
+}
+
+// Conditional wraps headersFunc so that requests carry If-None-Match and
+// If-Modified-Since from validators. An unchanged resource is then answered
+// with 304, which IsNotModified recognizes.
+func Conditional(validators Validators, headersFunc HeaderFunc) HeaderFunc {
+	return func(req *http.Request, path string, body []byte, client Client, t time.Time) {
+		if validators.ETag != "" {
+			req.Header.Set("If-None-Match", validators.ETag)
+		}
+		if !validators.LastModified.IsZero() {
+			req.Header.Set("If-Modified-Since", validators.LastModified.UTC().Format(http.TimeFormat))
+		}
+		headersFunc(req, path, body, client, t)
+	}
+}
+
+// IsNotModified reports whether err is a 304 answer to a conditional request.
+func IsNotModified(err error) bool {
+	var apiErr *ApiError
+	return errors.As(err, &apiErr) && apiErr.CodeReceived == http.StatusNotModified
+}
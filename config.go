@@ -0,0 +1,162 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Config holds optional, client-wide call behavior. The zero value changes
+// nothing about how calls are made.
+type Config struct {
+	// RateLimiter, when set, admits calls against a request budget before
+	// they reach the Scheduler. A TokenBucket serves PriorityCritical calls
+	// ahead of queued lower-priority ones.
+	RateLimiter RateLimiter
+
+	// Scheduler, when set, admits calls through a priority-aware queue.
+	Scheduler *Scheduler
+
+	// Tracker, when set, records outstanding calls so they can be listed and
+	// canceled.
+	Tracker *InFlightTracker
+
+	// SignatureExpiry, when set, refuses to send requests whose signature
+	// has aged past a limit and can stamp an expiry header on each request.
+	SignatureExpiry *SignatureExpiry
+
+	// Middleware wraps every call, first entry outermost.
+	Middleware []Middleware
+
+	// ResponseBufferPool, when set, supplies the buffers response bodies are
+	// read into; they are returned once the body has been decoded. Middleware
+	// that keeps an ApiResponse's Body() past the call must copy it.
+	ResponseBufferPool *BufferPool
+
+	// Clock supplies the time passed to HeaderFuncs for signing, e.g. a
+	// TimeSync, and the time read by retries, the CircuitBreaker, the
+	// ReadFallback, and an Outbox using this client. A TimerClock, such as
+	// testutil.FakeClock, also times retry waits and Outbox redeliveries.
+	// Defaults to SystemClock.
+	Clock Clock
+
+	// Metrics, when set, observes every call. Paths are recorded as set by
+	// WithPathTemplate or, failing that, with identifier-like segments
+	// replaced by TemplatePath.
+	Metrics MetricsRecorder
+
+	// MetricsRawPaths records untemplated paths. Only enable it for APIs
+	// whose paths carry no identifiers.
+	MetricsRawPaths bool
+
+	// GuardSignedRequests fails calls with a *SigningError, instead of
+	// sending them, when the HeaderFunc left the method, URL, or body
+	// different from what it was given to sign. The check runs just before
+	// the request is sent, after RequestCompression; a body it compressed
+	// is decompressed and compared with the signed, uncompressed body.
+	GuardSignedRequests bool
+
+	// Executor, when set, sends requests instead of the client's
+	// http.Client.
+	Executor Executor
+
+	// StrictDecoding fails calls with an *UnknownFieldsError when a JSON
+	// response has fields the response type does not declare.
+	StrictDecoding bool
+
+	// UnknownFields, when set, records unknown response fields by path
+	// without failing calls.
+	UnknownFields *UnknownFieldReport
+
+	// RequestCompression, when set, gzips large request bodies for hosts
+	// that accept them.
+	RequestCompression *RequestCompression
+
+	// Logger, when set, receives each attempt's request and response at
+	// Debug level, with credentials redacted.
+	Logger *slog.Logger
+
+	// Hooks, when set, are called at points within each call.
+	Hooks *Hooks
+
+	// Retry, when set, retries calls that fail transiently. Each attempt
+	// passes through the kill switch, scheduler, and signing again.
+	Retry *RetryPolicy
+
+	// CircuitBreaker, when set, fails calls locally while the API is failing
+	// persistently.
+	CircuitBreaker *CircuitBreaker
+
+	// ReadFallback, when set, moves reads to a secondary base URL while the
+	// primary is failing.
+	ReadFallback *ReadFallback
+}
+
+func (c *Config) now() time.Time {
+	return c.clock().Now()
+}
+
+func (c *Config) clock() Clock {
+	if c.Clock == nil {
+		return SystemClock
+	}
+	return c.Clock
+}
+
+// Use appends middleware to the chain, innermost last. Like the rest of the
+// Config, it must not be changed once the client is in use.
+func (c *Config) Use(middleware ...Middleware) {
+	c.Middleware = append(c.Middleware, middleware...)
+}
+
+// InFlight returns the calls currently outstanding, or nil when no Tracker is
+// configured.
+func (c *Config) InFlight() []InFlightRequest {
+	if c.Tracker == nil {
+		return nil
+	}
+	return c.Tracker.InFlight()
+}
+
+// CancelAll aborts every outstanding call and returns how many were canceled.
+func (c *Config) CancelAll() int {
+	if c.Tracker == nil {
+		return 0
+	}
+	return c.Tracker.CancelAll()
+}
+
+// ConfigurableClient is implemented by clients that carry a Config. Clients
+// that only implement Client get the default behavior.
+type ConfigurableClient interface {
+	Client
+	Config() *Config
+}
+
+func configOf(client Client) *Config {
+	if c, ok := client.(ConfigurableClient); ok {
+		if config := c.Config(); config != nil {
+			return config
+		}
+	}
+	return defaultConfig
+}
+
+// defaultConfig is shared by clients without a Config and must not be
+// modified.
+var defaultConfig = &Config{}
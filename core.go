@@ -21,9 +21,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 )
 
@@ -34,21 +36,148 @@ type Client interface {
 	HttpClient() *http.Client
 }
 
-type apiRequest struct {
+type ApiRequest struct {
 	Path                    string
 	Query                   string
 	HttpMethod              string
 	Body                    []byte
 	ExpectedHttpStatusCodes []int
 	Client                  Client
+
+	// baseUrl overrides the client's base URL, e.g. for a ReadFallback.
+	baseUrl string
+}
+
+func (r *ApiRequest) url() string {
+	if r.baseUrl != "" {
+		return r.baseUrl + r.Path + r.Query
+	}
+	return r.Client.HttpBaseUrl() + r.Path + r.Query
 }
 
+// ApiResponse is the result of a call. It is read through its methods so the
+// representation can change without breaking callers.
 type ApiResponse struct {
-	Request        *apiRequest
-	Body           []byte
-	HttpStatusCode int
-	HttpStatusMsg  string
-	Error          *ApiError
+	request    *ApiRequest
+	body       []byte
+	statusCode int
+	status     string
+	header     http.Header
+	err        *ApiError
+	stale      bool
+	duration   time.Duration
+
+	attemptStatusCodes []int
+
+	buf  *bytes.Buffer
+	pool *BufferPool
+}
+
+// NewApiResponse returns a successful response, for middleware and tests that
+// answer calls without reaching the API.
+func NewApiResponse(request *ApiRequest, statusCode int, header http.Header, body []byte) *ApiResponse {
+	return &ApiResponse{
+		request:    request,
+		body:       body,
+		statusCode: statusCode,
+		status:     fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		header:     header,
+
+		attemptStatusCodes: []int{statusCode},
+	}
+}
+
+// NewApiErrorResponse returns a failed response carrying err.
+func NewApiErrorResponse(request *ApiRequest, err *ApiError) *ApiResponse {
+	return &ApiResponse{
+		request:    request,
+		statusCode: err.CodeReceived,
+		err:        err,
+
+		attemptStatusCodes: []int{err.CodeReceived},
+	}
+}
+
+// StatusCode is the HTTP status code, or 0 when no response was received.
+func (r *ApiResponse) StatusCode() int {
+	return r.statusCode
+}
+
+// Status is the HTTP status line, such as "200 OK".
+func (r *ApiResponse) Status() string {
+	return r.status
+}
+
+// Header returns a copy of the response headers.
+func (r *ApiResponse) Header() http.Header {
+	return r.header.Clone()
+}
+
+// Body is the raw response body. It must not be modified, and is only valid
+// until the call that produced it returns.
+func (r *ApiResponse) Body() []byte {
+	return r.body
+}
+
+// Err is the call's error, or nil when it succeeded.
+func (r *ApiResponse) Err() *ApiError {
+	return r.err
+}
+
+// Stale reports whether the response was served from a cache in place of a
+// failed or slow call.
+func (r *ApiResponse) Stale() bool {
+	return r.stale
+}
+
+// Duration is the wall-clock time the call took.
+func (r *ApiResponse) Duration() time.Duration {
+	return r.duration
+}
+
+// Attempts is the number of times the request was sent.
+func (r *ApiResponse) Attempts() int {
+	return len(r.attemptStatusCodes)
+}
+
+// AttemptStatusCodes returns the status code of each attempt in order, with 0
+// for attempts that received no response.
+func (r *ApiResponse) AttemptStatusCodes() []int {
+	return append([]int(nil), r.attemptStatusCodes...)
+}
+
+func (r *ApiResponse) readBody(res *http.Response, pool *BufferPool) ([]byte, error) {
+	if pool == nil {
+		return ioutil.ReadAll(res.Body)
+	}
+
+	// Content-Length comes from the server, so it only sizes the buffer up
+	// to the largest class; ReadFrom grows it if the body is really larger.
+	size := int(res.ContentLength)
+	if size < 0 {
+		size = 0
+	}
+	if largest := pool.largestClass(); size > largest {
+		size = largest
+	}
+
+	r.buf = pool.Get(size)
+	r.pool = pool
+	if _, err := r.buf.ReadFrom(res.Body); err != nil {
+		return nil, err
+	}
+	return r.buf.Bytes(), nil
+}
+
+// release returns a pooled body buffer. Body must not be used afterwards.
+func (r *ApiResponse) release() {
+	if r.buf == nil {
+		return
+	}
+	r.pool.Put(r.buf)
+	r.buf = nil
+	r.pool = nil
+	r.body = nil
 }
 
 type ApiError struct {
@@ -56,15 +185,34 @@ type ApiError struct {
 	CodeExpected []int  `json:"-"`
 	CodeReceived int    `json:"-"`
 	ParsedUrl    string `json:"-"`
+	Err          error  `json:"-"`
+
+	// Duration, Attempts, and AttemptStatusCodes describe the call that
+	// failed, across retries.
+	Duration           time.Duration `json:"-"`
+	Attempts           int           `json:"-"`
+	AttemptStatusCodes []int         `json:"-"`
+
+	// RetryAfter is the wait the server asked for with Retry-After on a 429
+	// or 503, or zero when it did not say.
+	RetryAfter time.Duration `json:"-"`
 }
 
 func (e *ApiError) Error() string {
 	return fmt.Sprintf("Unexpected response: %s, Expected Status Codes: %v, Received Status Code: %d, URL: %s", e.Message, e.CodeExpected, e.CodeReceived, e.ParsedUrl)
 }
 
+// Unwrap returns the underlying error when the call failed before a response
+// was received.
+func (e *ApiError) Unwrap() error {
+	return e.Err
+}
+
 type HeaderFunc func(req *http.Request, path string, body []byte, client Client, t time.Time)
 
-func Post(
+// HttpPost makes a POST call expecting 200, decoding the body into
+// response.
+func HttpPost(
 	ctx context.Context,
 	client Client,
 	path,
@@ -76,7 +224,25 @@ func Post(
 	return call(ctx, client, path, query, http.MethodPost, []int{http.StatusOK}, request, response, headersFunc)
 }
 
-func Get(
+// Post forwards to HttpPost.
+//
+// Deprecated: use HttpPost.
+func Post(
+	ctx context.Context,
+	client Client,
+	path,
+	query string,
+	request,
+	response interface{},
+	headersFunc HeaderFunc,
+) error {
+	warnDeprecated(client, "Post", "HttpPost")
+	return HttpPost(ctx, client, path, query, request, response, headersFunc)
+}
+
+// HttpGet makes a GET call expecting 200, decoding the body into
+// response.
+func HttpGet(
 	ctx context.Context,
 	client Client,
 	path,
@@ -88,7 +254,25 @@ func Get(
 	return call(ctx, client, path, query, http.MethodGet, []int{http.StatusOK}, request, response, headersFunc)
 }
 
-func Put(
+// Get forwards to HttpGet.
+//
+// Deprecated: use HttpGet.
+func Get(
+	ctx context.Context,
+	client Client,
+	path,
+	query string,
+	request,
+	response interface{},
+	headersFunc HeaderFunc,
+) error {
+	warnDeprecated(client, "Get", "HttpGet")
+	return HttpGet(ctx, client, path, query, request, response, headersFunc)
+}
+
+// HttpPut makes a PUT call expecting 200, decoding the body into
+// response.
+func HttpPut(
 	ctx context.Context,
 	client Client,
 	path,
@@ -100,7 +284,25 @@ func Put(
 	return call(ctx, client, path, query, http.MethodPut, []int{http.StatusOK}, request, response, headersFunc)
 }
 
-func Delete(
+// Put forwards to HttpPut.
+//
+// Deprecated: use HttpPut.
+func Put(
+	ctx context.Context,
+	client Client,
+	path,
+	query string,
+	request,
+	response interface{},
+	headersFunc HeaderFunc,
+) error {
+	warnDeprecated(client, "Put", "HttpPut")
+	return HttpPut(ctx, client, path, query, request, response, headersFunc)
+}
+
+// HttpDelete makes a DELETE call expecting 200, decoding the body into
+// response.
+func HttpDelete(
 	ctx context.Context,
 	client Client,
 	path,
@@ -112,7 +314,25 @@ func Delete(
 	return call(ctx, client, path, query, http.MethodDelete, []int{http.StatusOK}, request, response, headersFunc)
 }
 
-func Patch(
+// Delete forwards to HttpDelete.
+//
+// Deprecated: use HttpDelete.
+func Delete(
+	ctx context.Context,
+	client Client,
+	path,
+	query string,
+	request,
+	response interface{},
+	headersFunc HeaderFunc,
+) error {
+	warnDeprecated(client, "Delete", "HttpDelete")
+	return HttpDelete(ctx, client, path, query, request, response, headersFunc)
+}
+
+// HttpPatch makes a PATCH call expecting 200, decoding the body into
+// response.
+func HttpPatch(
 	ctx context.Context,
 	client Client,
 	path,
@@ -124,6 +344,47 @@ func Patch(
 	return call(ctx, client, path, query, http.MethodPatch, []int{http.StatusOK}, request, response, headersFunc)
 }
 
+// Patch forwards to HttpPatch.
+//
+// Deprecated: use HttpPatch.
+func Patch(
+	ctx context.Context,
+	client Client,
+	path,
+	query string,
+	request,
+	response interface{},
+	headersFunc HeaderFunc,
+) error {
+	warnDeprecated(client, "Patch", "HttpPatch")
+	return HttpPatch(ctx, client, path, query, request, response, headersFunc)
+}
+
+// HttpHead returns the headers of a HEAD request, e.g. to check that a
+// resource exists. There is no body to decode.
+func HttpHead(
+	ctx context.Context,
+	client Client,
+	path,
+	query string,
+	headersFunc HeaderFunc,
+) (http.Header, error) {
+	return callWithHeader(ctx, client, path, query, http.MethodHead, []int{http.StatusOK}, nil, nil, headersFunc)
+}
+
+// HttpOptions returns the headers of an OPTIONS request, such as Allow, and
+// decodes the body into response when there is one.
+func HttpOptions(
+	ctx context.Context,
+	client Client,
+	path,
+	query string,
+	response interface{},
+	headersFunc HeaderFunc,
+) (http.Header, error) {
+	return callWithHeader(ctx, client, path, query, http.MethodOptions, []int{http.StatusOK, http.StatusNoContent}, nil, response, headersFunc)
+}
+
 func call(
 	ctx context.Context,
 	client Client,
@@ -135,15 +396,36 @@ func call(
 	response interface{},
 	headersFunc HeaderFunc,
 ) error {
+	_, err := callWithHeader(ctx, client, path, query, httpMethod, expectedHttpStatusCodes, request, response, headersFunc)
+	return err
+}
 
-	body, err := json.Marshal(request)
+// callWithHeader is call that also returns the response headers, which are
+// nil when no response was received.
+func callWithHeader(
+	ctx context.Context,
+	client Client,
+	path,
+	query,
+	httpMethod string,
+	expectedHttpStatusCodes []int,
+	request,
+	response interface{},
+	headersFunc HeaderFunc,
+) (http.Header, error) {
+
+	body, err := marshalRequest(request)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	resp := makeCall(
+	config := configOf(client)
+	ctx, done := config.Tracker.track(ctx, httpMethod, path)
+	defer done()
+
+	resp := chainMiddleware(config.Middleware, config.CircuitBreaker.wrap(config.ReadFallback.wrap(retrying(config.Retry, makeCall))))(
 		ctx,
-		&apiRequest{
+		&ApiRequest{
 			Path:                    path,
 			Query:                   query,
 			HttpMethod:              httpMethod,
@@ -154,94 +436,305 @@ func call(
 		headersFunc,
 	)
 
-	if resp.Error != nil {
-		return resp.Error
+	defer resp.release()
+
+	if resp.err != nil {
+		return resp.header, typedError(resp.err)
 	}
 
-	if err := json.Unmarshal(resp.Body, response); err != nil {
-		return err
+	contentType := resp.header.Get("Content-Type")
+	if err := unmarshalResponse(contentType, resp.body, response); err != nil {
+		return resp.header, err
 	}
+	return resp.header, checkUnknownFields(ctx, config, path, contentType, resp.body, response)
+}
 
-	return nil
+// marshalRequest encodes request as JSON. Bodies that are already encoded,
+// as []byte or json.RawMessage, are sent as is.
+func marshalRequest(request interface{}) ([]byte, error) {
+	switch r := request.(type) {
+	case []byte:
+		return r, nil
+	case json.RawMessage:
+		return r, nil
+	default:
+		return json.Marshal(request)
+	}
 }
 
-func makeCall(ctx context.Context, request *apiRequest, headersFunc HeaderFunc) *ApiResponse {
+// unmarshalResponse decodes body into response with the codec for
+// contentType. A nil response or an empty body leaves response untouched, and
+// *[]byte receives a copy of the raw bytes.
+func unmarshalResponse(contentType string, body []byte, response interface{}) error {
+	switch r := response.(type) {
+	case nil:
+		return nil
+	case *[]byte:
+		*r = bytes.Clone(body)
+		return nil
+	default:
+		if len(body) == 0 {
+			return nil
+		}
+		return codecFor(contentType).Unmarshal(body, response)
+	}
+}
+
+func makeCall(ctx context.Context, request *ApiRequest, headersFunc HeaderFunc) *ApiResponse {
 
 	response := &ApiResponse{
-		Request: request,
+		request: request,
+	}
+
+	config := configOf(request.Client)
+	start := time.Now()
+	var res *http.Response
+	defer func() {
+		response.duration = time.Since(start)
+		response.attemptStatusCodes = []int{response.statusCode}
+		if response.err != nil {
+			response.err.Duration = response.duration
+			response.err.Attempts = 1
+			response.err.AttemptStatusCodes = response.AttemptStatusCodes()
+		}
+		recordRequest(ctx, config, request, response.statusCode, start)
+		logExchange(ctx, config.Logger, request, res, response.body, response.duration, response.err)
+	}()
+
+	res, finish, apiErr := send(ctx, request, headersFunc)
+	if apiErr != nil {
+		response.err = apiErr
+		return response
+	}
+	defer finish()
+
+	defer res.Body.Close()
+	body, err := response.readBody(res, config.ResponseBufferPool)
+	if err != nil {
+		err = classifyError(res.Request.Context(), err)
+		response.err = &ApiError{
+			Message:      err.Error(),
+			ParsedUrl:    request.url(),
+			CodeReceived: 0,
+			Err:          err,
+		}
+		return response
 	}
 
-	callUrl := fmt.Sprintf("%s%s%s", request.Client.HttpBaseUrl(), request.Path, request.Query)
+	response.body = body
+	response.statusCode = res.StatusCode
+	response.status = res.Status
+	response.header = res.Header
+
+	if !isExpectedStatusCode(request, res.StatusCode) {
+		response.err = unexpectedStatusError(request, res, body)
+	}
+
+	return response
+}
+
+// send builds, signs, and executes request, returning the response with its
+// body unread. finish must be called once the body has been consumed.
+func send(ctx context.Context, request *ApiRequest, headersFunc HeaderFunc) (res *http.Response, finish func(), apiErr *ApiError) {
+
+	callUrl := request.url()
 
 	parsedUrl, err := url.Parse(callUrl)
 	if err != nil {
-		response.Error = &ApiError{
+		return nil, nil, &ApiError{
 			Message:      fmt.Sprintf("invalid URL: %s - %v", callUrl, err),
 			ParsedUrl:    callUrl,
 			CodeReceived: 0,
+			Err:          fmt.Errorf("%w: %w", ErrInvalidUrl, err),
 		}
-		return response
 	}
 
+	if err := DefaultKillSwitch.Allow(ctx, request.HttpMethod, request.Path); err != nil {
+		err = classifyError(ctx, err)
+		return nil, nil, &ApiError{
+			Message:      err.Error(),
+			ParsedUrl:    callUrl,
+			CodeReceived: 0,
+			Err:          err,
+		}
+	}
+
+	config := configOf(request.Client)
+
+	var cleanup []func()
+	finish = func() {
+		for i := len(cleanup) - 1; i >= 0; i-- {
+			cleanup[i]()
+		}
+	}
+
+	if limiter := config.RateLimiter; limiter != nil {
+		if err := limiter.Wait(ctx); err != nil {
+			err = classifyError(ctx, err)
+			finish()
+			return nil, nil, &ApiError{
+				Message:      err.Error(),
+				ParsedUrl:    callUrl,
+				CodeReceived: 0,
+				Err:          err,
+			}
+		}
+	}
+
+	if scheduler := config.Scheduler; scheduler != nil {
+		release, err := scheduler.Acquire(ctx, PriorityFromContext(ctx))
+		if err != nil {
+			err = classifyError(ctx, err)
+			finish()
+			return nil, nil, &ApiError{
+				Message:      err.Error(),
+				ParsedUrl:    callUrl,
+				CodeReceived: 0,
+				Err:          err,
+			}
+		}
+		cleanup = append(cleanup, release)
+	}
+
+	ctx = config.Hooks.withClientTrace(ctx, request)
+
 	var requestBody []byte
-	if request.HttpMethod == http.MethodPost || request.HttpMethod == http.MethodPut || request.HttpMethod == http.MethodPatch {
+	if sendsBody(request.HttpMethod) {
 		requestBody = request.Body
 	}
 
+	ctx, signing := withSigningFailure(ctx)
 	req, err := http.NewRequestWithContext(ctx, request.HttpMethod, callUrl, bytes.NewReader(requestBody))
 	if err != nil {
-		response.Error = &ApiError{
+		finish()
+		return nil, nil, &ApiError{
 			Message:      err.Error(),
+			ParsedUrl:    callUrl,
 			CodeReceived: 0,
+			Err:          err,
 		}
-		return response
 	}
 
-	headersFunc(req, parsedUrl.Path, requestBody, request.Client, time.Now())
+	if accept := AcceptFromContext(ctx); accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if contentType := ContentTypeFromContext(ctx); contentType != "" && len(requestBody) > 0 {
+		req.Header.Set("Content-Type", contentType)
+	}
 
-	res, err := request.Client.HttpClient().Do(req)
+	signedAt := config.now()
+	if config.SignatureExpiry != nil {
+		config.SignatureExpiry.apply(req, signedAt)
+	}
+
+	headersFunc(req, parsedUrl.Path, requestBody, request.Client, signedAt)
+	if signing.err != nil {
+		finish()
+		err := &SigningError{Err: signing.err}
+		return nil, nil, &ApiError{
+			Message:      err.Error(),
+			ParsedUrl:    callUrl,
+			CodeReceived: 0,
+			Err:          err,
+		}
+	}
+
+	compressed, err := config.RequestCompression.compress(ctx, req, requestBody, request, config.Hooks)
 	if err != nil {
-		response.Error = &ApiError{
+		finish()
+		return nil, nil, &ApiError{
 			Message:      err.Error(),
+			ParsedUrl:    callUrl,
 			CodeReceived: 0,
+			Err:          err,
 		}
-		return response
 	}
 
-	defer res.Body.Close()
-	body, err := ioutil.ReadAll(res.Body)
+	res, err = transmit(ctx, config, request, req, parsedUrl, requestBody, signedAt, compressed)
+	if err == nil && compressed && res.StatusCode == http.StatusUnsupportedMediaType {
+		// The host does not take compressed bodies. observe records that,
+		// and the signed body is sent again as it was signed.
+		config.RequestCompression.observe(req, res)
+		io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+		decompressBody(req, requestBody)
+		res, err = transmit(ctx, config, request, req, parsedUrl, requestBody, signedAt, false)
+	}
 	if err != nil {
-		response.Error = &ApiError{
+		finish()
+		return nil, nil, &ApiError{
 			Message:      err.Error(),
+			ParsedUrl:    callUrl,
 			CodeReceived: 0,
+			Err:          err,
 		}
-		return response
 	}
+	config.RequestCompression.observe(req, res)
 
-	response.Body = body
-	response.HttpStatusCode = res.StatusCode
-	response.HttpStatusMsg = res.Status
+	return res, finish, nil
+}
 
-	isExpectedStatusCode := false
-	for _, code := range request.ExpectedHttpStatusCodes {
-		if res.StatusCode == code {
-			isExpectedStatusCode = true
-			break
+// transmit makes the last checks on a signed request and sends it. Errors
+// are ready to be wrapped in an ApiError.
+func transmit(
+	ctx context.Context,
+	config *Config,
+	request *ApiRequest,
+	req *http.Request,
+	signedUrl *url.URL,
+	signedBody []byte,
+	signedAt time.Time,
+	compressed bool,
+) (*http.Response, error) {
+	if config.GuardSignedRequests {
+		if err := verifySignedRequest(req, request.HttpMethod, signedUrl, signedBody, compressed); err != nil {
+			return nil, &SigningError{Err: err}
+		}
+	}
+
+	if config.SignatureExpiry != nil {
+		if err := config.SignatureExpiry.check(signedAt, config.now()); err != nil {
+			return nil, err
 		}
 	}
 
-	if !isExpectedStatusCode {
-		var apiErr ApiError
-		if jsonErr := json.Unmarshal(response.Body, &apiErr); jsonErr != nil {
-			apiErr.Message = string(body)
+	res, err := execute(config, request.Client, req)
+	if err != nil {
+		return nil, classifyError(ctx, err)
+	}
+	return res, nil
+}
+
+// sendsBody reports whether calls with httpMethod send the request body.
+func sendsBody(httpMethod string) bool {
+	return httpMethod == http.MethodPost || httpMethod == http.MethodPut || httpMethod == http.MethodPatch
+}
+
+func isExpectedStatusCode(request *ApiRequest, statusCode int) bool {
+	for _, code := range request.ExpectedHttpStatusCodes {
+		if statusCode == code {
+			return true
 		}
+	}
+	return false
+}
 
-		apiErr.CodeExpected = request.ExpectedHttpStatusCodes
-		apiErr.CodeReceived = res.StatusCode
-		apiErr.ParsedUrl = callUrl
+// unexpectedStatusError builds the error for a response with an unexpected
+// status. JSON bodies supply the message field; other bodies, such as HTML
+// from a proxy or CSV from a report endpoint, are used as plain text.
+func unexpectedStatusError(request *ApiRequest, res *http.Response, body []byte) *ApiError {
+	var apiErr ApiError
+	if !isJsonContentType(res.Header.Get("Content-Type")) || json.Unmarshal(body, &apiErr) != nil || apiErr.Message == "" {
+		apiErr.Message = strings.TrimSpace(string(body))
+	}
+
+	apiErr.CodeExpected = request.ExpectedHttpStatusCodes
+	apiErr.CodeReceived = res.StatusCode
+	apiErr.ParsedUrl = request.url()
 
-		response.Error = &apiErr
+	if res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusServiceUnavailable {
+		apiErr.RetryAfter, _ = ParseRetryAfter(res.Header.Get("Retry-After"), time.Now())
 	}
 
-	return response
+	return &apiErr
 }
@@ -0,0 +1,127 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type benchmarkOrder struct {
+	ClientOrderId string `json:"client_order_id"`
+	ProductId     string `json:"product_id"`
+	Side          string `json:"side"`
+	Size          string `json:"size"`
+	LimitPrice    string `json:"limit_price"`
+}
+
+type benchmarkOrderResponse struct {
+	OrderId string `json:"order_id"`
+	Status  string `json:"status"`
+}
+
+const benchmarkResponseBody = `{"order_id":"11111111-1111-1111-1111-111111111111","status":"OPEN"}`
+
+func noHeaders(*http.Request, string, []byte, Client, time.Time) {}
+
+func TestReadBodyIgnoresOversizedContentLength(t *testing.T) {
+	pool := NewBufferPool(1 << 10)
+	res := &http.Response{
+		ContentLength: 9e18,
+		Body:          io.NopCloser(strings.NewReader(benchmarkResponseBody + strings.Repeat(" ", 2<<10))),
+	}
+
+	var response ApiResponse
+	body, err := response.readBody(res, pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(body), benchmarkResponseBody) || len(body) != len(benchmarkResponseBody)+2<<10 {
+		t.Fatalf("read %d bytes, want the whole %d byte body", len(body), len(benchmarkResponseBody)+2<<10)
+	}
+	if stats := pool.Stats(); stats.Oversized != 0 {
+		t.Fatalf("Content-Length sized an unpooled buffer: %+v", stats)
+	}
+}
+
+func newBenchmarkClient(b *testing.B) *RestClient {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(benchmarkResponseBody))
+	}))
+	b.Cleanup(server.Close)
+	return NewClient(server.URL, server.Client())
+}
+
+var benchmarkOrderRequest = &benchmarkOrder{
+	ClientOrderId: "0000-1111",
+	ProductId:     "BTC-USD",
+	Side:          "BUY",
+	Size:          "0.01",
+	LimitPrice:    "50000.00",
+}
+
+// BenchmarkCallStruct posts a struct and decodes into a struct.
+func BenchmarkCallStruct(b *testing.B) {
+	client := newBenchmarkClient(b)
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var response benchmarkOrderResponse
+		if err := HttpPost(ctx, client, "/orders", EmptyQueryParams, benchmarkOrderRequest, &response, noHeaders); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkCallRawBytes posts an encoded body and keeps the raw response.
+func BenchmarkCallRawBytes(b *testing.B) {
+	client := newBenchmarkClient(b)
+	ctx := context.Background()
+	body, _ := json.Marshal(benchmarkOrderRequest)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var response []byte
+		if err := HttpPost(ctx, client, "/orders", EmptyQueryParams, body, &response, noHeaders); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkCallGeneric posts through the generic entry point.
+func BenchmarkCallGeneric(b *testing.B) {
+	client := newBenchmarkClient(b)
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := PostAs[*benchmarkOrder, benchmarkOrderResponse](ctx, client, "/orders", EmptyQueryParams, benchmarkOrderRequest, noHeaders); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Credentials struct {
+	AccessKey  string `json:"accessKey"`
+	Passphrase string `json:"passphrase"`
+	SigningKey string `json:"signingKey"`
+}
+
+// ReadCredentialsFromEnv parses the credentials JSON held in the environment
+// variable varName.
+func ReadCredentialsFromEnv(varName string) (*Credentials, error) {
+	raw, ok := os.LookupEnv(varName)
+	if !ok || strings.TrimSpace(raw) == "" {
+		return nil, fmt.Errorf("environment variable %s is not set", varName)
+	}
+	credentials, err := parseCredentials([]byte(raw))
+	if err != nil {
+		return nil, fmt.Errorf("environment variable %s: %w", varName, err)
+	}
+	return credentials, nil
+}
+
+// ReadCredentialsFromFile parses the credentials JSON file at path.
+func ReadCredentialsFromFile(path string) (*Credentials, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading credentials file: %w", err)
+	}
+	credentials, err := parseCredentials(raw)
+	if err != nil {
+		return nil, fmt.Errorf("credentials file %s: %w", path, err)
+	}
+	return credentials, nil
+}
+
+// parseCredentials accepts the standard {"accessKey", "passphrase",
+// "signingKey"} blob and the {"name", "privateKey"} form of downloaded CDP
+// key files. Errors never include key material.
+func parseCredentials(raw []byte) (*Credentials, error) {
+	var blob struct {
+		Credentials
+		Name       string `json:"name"`
+		PrivateKey string `json:"privateKey"`
+	}
+	if err := json.Unmarshal(raw, &blob); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return nil, fmt.Errorf("invalid credentials JSON at offset %d", syntaxErr.Offset)
+		}
+		return nil, errors.New("invalid credentials JSON: expected an object with accessKey and signingKey")
+	}
+
+	credentials := blob.Credentials
+	if credentials.AccessKey == "" {
+		credentials.AccessKey = blob.Name
+	}
+	if credentials.SigningKey == "" {
+		credentials.SigningKey = blob.PrivateKey
+	}
+
+	switch {
+	case credentials.AccessKey == "":
+		return nil, errors.New("credentials are missing accessKey")
+	case credentials.SigningKey == "":
+		return nil, errors.New("credentials are missing signingKey")
+	case credentials.KeyFormat() == KeyFormatUnknown:
+		return nil, fmt.Errorf("credentials signingKey is an %s", KeyFormatUnknown)
+	}
+	return &credentials, nil
+}
+
+// KeyFormat is the kind of signing key held by Credentials.
+type KeyFormat int
+
+const (
+	KeyFormatUnknown KeyFormat = iota
+	KeyFormatHmac
+	KeyFormatEcPem
+	KeyFormatEd25519
+)
+
+func (f KeyFormat) String() string {
+	switch f {
+	case KeyFormatHmac:
+		return "HMAC secret"
+	case KeyFormatEcPem:
+		return "ECDSA CDP key"
+	case KeyFormatEd25519:
+		return "Ed25519 CDP key"
+	default:
+		return "unrecognized key"
+	}
+}
+
+// KeyFormat detects the kind of signing key. EC keys are PEM encoded, Ed25519
+// keys are base64 private keys whose second half is the derived public key,
+// and any other non-empty key is treated as an HMAC secret.
+func (c *Credentials) KeyFormat() KeyFormat {
+	key := strings.TrimSpace(c.SigningKey)
+	switch {
+	case key == "":
+		return KeyFormatUnknown
+	case strings.Contains(key, "-----BEGIN"):
+		if strings.Contains(key, "PRIVATE KEY") {
+			return KeyFormatEcPem
+		}
+		return KeyFormatUnknown
+	case isEd25519PrivateKey(key):
+		return KeyFormatEd25519
+	default:
+		return KeyFormatHmac
+	}
+}
+
+// RequireKeyFormat returns a *KeyFormatError unless the signing key is one of
+// allowed, so a client built with the wrong kind of key fails at construction
+// instead of with a 401 from the API.
+func (c *Credentials) RequireKeyFormat(allowed ...KeyFormat) error {
+	got := c.KeyFormat()
+	for _, f := range allowed {
+		if got == f {
+			return nil
+		}
+	}
+	return &KeyFormatError{Required: allowed, Got: got}
+}
+
+// KeyFormatError reports credentials holding the wrong kind of signing key.
+type KeyFormatError struct {
+	Required []KeyFormat
+	Got      KeyFormat
+}
+
+func (e *KeyFormatError) Error() string {
+	required := make([]string, len(e.Required))
+	for i, f := range e.Required {
+		required[i] = f.String()
+	}
+	if e.Got == KeyFormatUnknown {
+		return fmt.Sprintf("this endpoint requires an %s, got an empty or unrecognized signing key", strings.Join(required, " or "))
+	}
+	return fmt.Sprintf("this endpoint requires an %s, got %s", strings.Join(required, " or "), e.Got)
+}
+
+func isEd25519PrivateKey(key string) bool {
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(raw) != ed25519.PrivateKeySize {
+		return false
+	}
+	derived := ed25519.NewKeyFromSeed(raw[:ed25519.SeedSize])
+	return bytes.Equal(derived[ed25519.SeedSize:], raw[ed25519.SeedSize:])
+}
+
+// HmacSignature returns the CB-ACCESS-SIGN value used by Coinbase Exchange
+// and Coinbase International Exchange: the base64 encoded HMAC-SHA256 of
+// timestamp + method + requestPath + body, keyed with the base64-decoded
+// signing key. requestPath includes the query string, if any.
+func (c *Credentials) HmacSignature(timestamp, method, requestPath string, body []byte) (string, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(c.SigningKey))
+	if err != nil {
+		return "", fmt.Errorf("HMAC signing key is not base64: %w", err)
+	}
+
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(timestamp + method + requestPath))
+	h.Write(body)
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// SignedWebSocketUrl adds timestamp, signature, and key query parameters (and
+// passphrase when set) to rawUrl, for websocket endpoints that authenticate
+// the handshake through the query string rather than headers. The signature
+// covers timestamp + "GET" + the URL path.
+func SignedWebSocketUrl(credentials *Credentials, rawUrl string, t time.Time) (string, error) {
+	if credentials == nil {
+		return "", errors.New("credentials are required to sign a websocket url")
+	}
+
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return "", fmt.Errorf("invalid websocket url: %s - %w", rawUrl, err)
+	}
+
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	signature, err := credentials.HmacSignature(timestamp, http.MethodGet, u.EscapedPath(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	q := u.Query()
+	q.Set("timestamp", timestamp)
+	q.Set("key", credentials.AccessKey)
+	q.Set("signature", signature)
+	if credentials.Passphrase != "" {
+		q.Set("passphrase", credentials.Passphrase)
+	}
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
@@ -0,0 +1,213 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"encoding"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+type csvField struct {
+	index  []int
+	layout string
+}
+
+// DecodeCsv reads CSV with a header row from r one record at a time, passing
+// each to fn as a T, so memory stays bounded regardless of the report's size.
+//
+// Columns map to fields by a `csv:"name"` tag, or by case-insensitive field
+// name; unmatched columns are ignored and a tag of "-" skips a field. Fields
+// may be strings, bools, numbers, time.Duration, time.Time (RFC 3339 unless a
+// tag such as `csv:"date,layout=2006-01-02"` sets the layout), or types
+// implementing encoding.TextUnmarshaler, which covers decimal types. Pointer
+// fields are left nil for empty cells. Returning an error from fn stops
+// decoding.
+func DecodeCsv[T any](r io.Reader, fn func(row T) error) error {
+	var zero T
+	rowType := reflect.TypeOf(zero)
+	if rowType == nil || rowType.Kind() != reflect.Struct {
+		return fmt.Errorf("DecodeCsv requires a struct type, got %T", zero)
+	}
+
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("reading CSV header: %w", err)
+	}
+	header = append([]string(nil), header...)
+	fields := mapCsvColumns(rowType, header)
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var row T
+		v := reflect.ValueOf(&row).Elem()
+		for i, cell := range record {
+			if i >= len(fields) || fields[i] == nil {
+				continue
+			}
+			if err := setCsvValue(v.FieldByIndex(fields[i].index), cell, fields[i].layout); err != nil {
+				return fmt.Errorf("CSV line %d, column %q: %w", line, header[i], err)
+			}
+		}
+
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+}
+
+// GetDecodeCsv streams a GET response through DecodeCsv.
+func GetDecodeCsv[T any](
+	ctx context.Context,
+	client Client,
+	path,
+	query string,
+	headersFunc HeaderFunc,
+	fn func(row T) error,
+) error {
+	res, err := GetStream(ctx, client, path, query, headersFunc)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	return DecodeCsv(res.Body, fn)
+}
+
+func mapCsvColumns(rowType reflect.Type, header []string) []*csvField {
+	byName := map[string]*csvField{}
+	for _, f := range reflect.VisibleFields(rowType) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+
+		name, layout := f.Name, ""
+		if tag, ok := f.Tag.Lookup("csv"); ok {
+			if tag == "-" {
+				continue
+			}
+			parts := strings.Split(tag, ",")
+			if parts[0] != "" {
+				name = parts[0]
+			}
+			for _, option := range parts[1:] {
+				if l, ok := strings.CutPrefix(option, "layout="); ok {
+					layout = l
+				}
+			}
+		}
+		byName[strings.ToLower(name)] = &csvField{index: f.Index, layout: layout}
+	}
+
+	fields := make([]*csvField, len(header))
+	for i, column := range header {
+		fields[i] = byName[strings.ToLower(strings.TrimSpace(column))]
+	}
+	return fields
+}
+
+func setCsvValue(v reflect.Value, cell, layout string) error {
+	if v.Kind() == reflect.Pointer {
+		if cell == "" {
+			return nil
+		}
+		v.Set(reflect.New(v.Type().Elem()))
+		v = v.Elem()
+	}
+
+	if cell == "" {
+		return nil
+	}
+
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) && v.Type() != timeType {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(cell))
+	}
+
+	switch {
+	case v.Type() == timeType:
+		if layout == "" {
+			layout = time.RFC3339Nano
+		}
+		t, err := time.Parse(layout, cell)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(cell)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(cell)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(cell)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(cell, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(cell, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(cell, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
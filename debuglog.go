@@ -0,0 +1,164 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const redacted = "[REDACTED]"
+
+// RedactedHeaders are the headers whose values are replaced in debug logs.
+var RedactedHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"CB-ACCESS-SIGN",
+	"CB-ACCESS-PASSPHRASE",
+	"X-Vault-Token",
+}
+
+// RedactedFields are the JSON body fields whose values are replaced in debug
+// logs, matched ignoring case, "_" and "-". String values holding a PEM
+// private key are replaced whatever their field.
+var RedactedFields = []string{
+	"passphrase",
+	"signingKey",
+	"privateKey",
+	"secret",
+	"password",
+}
+
+// maxLoggedBody caps each body in a debug log record.
+const maxLoggedBody = 4096
+
+// logExchange writes one Debug record for a call attempt with its request
+// and response, credentials redacted. res is nil when no response was
+// received.
+func logExchange(ctx context.Context, logger *slog.Logger, request *ApiRequest, res *http.Response, body []byte, duration time.Duration, apiErr *ApiError) {
+	if logger == nil || !logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("method", request.HttpMethod),
+		slog.String("url", request.url()),
+		slog.Duration("duration", duration),
+	}
+	if res != nil && res.Request != nil {
+		attrs = append(attrs, slog.Any("request_headers", redactHeaders(res.Request.Header)))
+	}
+	if sendsBody(request.HttpMethod) && len(request.Body) > 0 {
+		attrs = append(attrs, slog.String("request_body", redactBody(request.Body)))
+	}
+	if res != nil {
+		attrs = append(attrs,
+			slog.Int("status", res.StatusCode),
+			slog.Any("response_headers", redactHeaders(res.Header)),
+		)
+		if len(body) > 0 {
+			attrs = append(attrs, slog.String("response_body", redactBody(body)))
+		}
+	}
+	if apiErr != nil {
+		attrs = append(attrs, slog.String("error", apiErr.Message))
+	}
+
+	logger.LogAttrs(ctx, slog.LevelDebug, "http exchange", attrs...)
+}
+
+func redactHeaders(header http.Header) map[string]string {
+	redactedHeaders := make(map[string]string, len(header))
+	for name, values := range header {
+		value := strings.Join(values, ", ")
+		for _, r := range RedactedHeaders {
+			if strings.EqualFold(name, r) {
+				value = redacted
+				break
+			}
+		}
+		redactedHeaders[name] = value
+	}
+	return redactedHeaders
+}
+
+// redactBody returns body for logging with secret fields replaced. Bodies
+// that are not JSON are summarized by size, as secrets in them cannot be
+// found.
+func redactBody(body []byte) string {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return "[" + strconv.Itoa(len(body)) + " bytes]"
+	}
+
+	out, err := json.Marshal(redactValue(v))
+	if err != nil {
+		return "[" + strconv.Itoa(len(body)) + " bytes]"
+	}
+	if len(out) > maxLoggedBody {
+		return string(out[:maxLoggedBody]) + "...[truncated]"
+	}
+	return string(out)
+}
+
+func redactValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, field := range value {
+			if isRedactedField(key) {
+				value[key] = redacted
+				continue
+			}
+			value[key] = redactValue(field)
+		}
+		return value
+	case []interface{}:
+		for i, item := range value {
+			value[i] = redactValue(item)
+		}
+		return value
+	case string:
+		if strings.Contains(value, "PRIVATE KEY") {
+			return redacted
+		}
+		return value
+	default:
+		return value
+	}
+}
+
+var fieldNameNormalizer = strings.NewReplacer("_", "", "-", "")
+
+func isRedactedField(key string) bool {
+	normalized := fieldNameNormalizer.Replace(key)
+	for _, field := range RedactedFields {
+		if strings.EqualFold(normalized, fieldNameNormalizer.Replace(field)) {
+			return true
+		}
+	}
+	return false
+}
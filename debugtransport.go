@@ -0,0 +1,128 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"sync/atomic"
+)
+
+// DebugTransport dumps each request and response to Logger at Debug level
+// while enabled, with RedactedHeaders masked and bodies redacted as in
+// Config.Logger records. It sees requests as sent, after signing and any
+// transport-level changes, which helps when troubleshooting a 401.
+type DebugTransport struct {
+	// Next sends the requests. Defaults to http.DefaultTransport.
+	Next http.RoundTripper
+
+	// Logger receives the dumps. Defaults to slog.Default().
+	Logger *slog.Logger
+
+	enabled atomic.Bool
+}
+
+// SetEnabled turns dumping on or off. It is safe to call while requests
+// are in flight.
+func (t *DebugTransport) SetEnabled(enabled bool) {
+	t.enabled.Store(enabled)
+}
+
+func (t *DebugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	logger := t.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if !t.enabled.Load() || !logger.Enabled(req.Context(), slog.LevelDebug) {
+		return next.RoundTrip(req)
+	}
+
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	masked := req.Clone(req.Context())
+	masked.Header = maskHeaders(req.Header)
+	masked.Body = io.NopCloser(bytes.NewReader(body))
+	if dump, err := httputil.DumpRequestOut(masked, false); err == nil {
+		attrs := []any{slog.String("dump", string(dump))}
+		if len(body) > 0 {
+			attrs = append(attrs, slog.String("body", redactBody(body)))
+		}
+		logger.DebugContext(req.Context(), "http request dump", attrs...)
+	}
+
+	res, err := next.RoundTrip(req)
+	if err != nil {
+		logger.DebugContext(req.Context(), "http request failed", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	header := res.Header
+	res.Header = maskHeaders(header)
+	dump, dumpErr := httputil.DumpResponse(res, false)
+	res.Header = header
+	if dumpErr == nil {
+		attrs := []any{slog.String("dump", string(dump))}
+		if data, err := io.ReadAll(res.Body); err == nil {
+			res.Body.Close()
+			res.Body = io.NopCloser(bytes.NewReader(data))
+			if len(data) > 0 {
+				attrs = append(attrs, slog.String("body", redactBody(data)))
+			}
+		} else {
+			res.Body = io.NopCloser(io.MultiReader(bytes.NewReader(data), errReader{err}))
+		}
+		logger.DebugContext(req.Context(), "http response dump", attrs...)
+	}
+	return res, nil
+}
+
+// CloseIdleConnections closes idle connections of Next.
+func (t *DebugTransport) CloseIdleConnections() {
+	if c, ok := t.Next.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+// maskHeaders returns a copy of header with RedactedHeaders masked.
+func maskHeaders(header http.Header) http.Header {
+	masked := header.Clone()
+	for name, value := range redactHeaders(header) {
+		if value == redacted {
+			masked[name] = []string{redacted}
+		}
+	}
+	return masked
+}
+
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
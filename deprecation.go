@@ -0,0 +1,66 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"log/slog"
+	"sync"
+)
+
+// OnDeprecated is called the first time each deprecated function is used,
+// with the name of its replacement. When nil, a warning is logged through the
+// calling client's Config.Logger, or slog.Default() when it has none. Set it
+// to a no-op to silence the warnings.
+var OnDeprecated func(name, replacement string)
+
+var deprecations = struct {
+	sync.Mutex
+	calls map[string]uint64
+}{calls: map[string]uint64{}}
+
+// DeprecatedCalls returns how many times each deprecated function has been
+// called, to track what is left to migrate.
+func DeprecatedCalls() map[string]uint64 {
+	deprecations.Lock()
+	defer deprecations.Unlock()
+
+	calls := make(map[string]uint64, len(deprecations.calls))
+	for name, n := range deprecations.calls {
+		calls[name] = n
+	}
+	return calls
+}
+
+func warnDeprecated(client Client, name, replacement string) {
+	deprecations.Lock()
+	deprecations.calls[name]++
+	first := deprecations.calls[name] == 1
+	deprecations.Unlock()
+
+	if !first {
+		return
+	}
+	if OnDeprecated != nil {
+		OnDeprecated(name, replacement)
+		return
+	}
+	logger := configOf(client).Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger.Warn("core-go: deprecated function called", slog.String("function", "core."+name), slog.String("replacement", "core."+replacement))
+}
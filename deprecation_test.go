@@ -0,0 +1,35 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestWarnDeprecatedUsesClientLogger(t *testing.T) {
+	var logged bytes.Buffer
+	client := NewClient("https://api.example.com", nil)
+	client.Config().Logger = slog.New(slog.NewTextHandler(&logged, nil))
+
+	warnDeprecated(client, "OldCall", "NewCall")
+	if !strings.Contains(logged.String(), "function=core.OldCall") {
+		t.Fatalf("client logger got %q, want the deprecation warning", logged.String())
+	}
+}
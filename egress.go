@@ -0,0 +1,160 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/netip"
+	"strings"
+)
+
+// EgressCheck verifies that requests leave from an address on an API key's IP
+// allowlist, so a misrouted host fails fast instead of with opaque 401s.
+type EgressCheck struct {
+	// Allowlist holds the allowed addresses and CIDR ranges.
+	Allowlist []string
+
+	// EchoUrl, when set, is an endpoint answering with the caller's public
+	// address, as plain text or as JSON with an "ip" field. Without it the
+	// local address of the connection to the client's base URL is used,
+	// which is only the egress address when there is no NAT.
+	EchoUrl string
+}
+
+// EgressIpError reports an egress address missing from the allowlist.
+type EgressIpError struct {
+	Ip        netip.Addr
+	Allowlist []string
+}
+
+func (e *EgressIpError) Error() string {
+	return fmt.Sprintf("egress ip %s is not in the API key allowlist %v", e.Ip, e.Allowlist)
+}
+
+// EgressIp discovers the address requests from client leave from.
+func (c EgressCheck) EgressIp(ctx context.Context, client Client) (netip.Addr, error) {
+	target := c.EchoUrl
+	if target == "" {
+		target = client.HttpBaseUrl()
+	}
+
+	var local net.Addr
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			local = info.Conn.LocalAddr()
+		},
+	})
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("invalid egress check url: %s - %w", target, err)
+	}
+	res, err := client.HttpClient().Do(req)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("egress check request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if c.EchoUrl == "" {
+		if local == nil {
+			return netip.Addr{}, fmt.Errorf("egress check made no connection to %s", target)
+		}
+		addrPort, err := netip.ParseAddrPort(local.String())
+		if err != nil {
+			return netip.Addr{}, fmt.Errorf("unable to parse local address %s - %w", local, err)
+		}
+		return addrPort.Addr().Unmap(), nil
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return netip.Addr{}, fmt.Errorf("egress echo endpoint answered %d", res.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1024))
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	return parseEchoedIp(body)
+}
+
+// Verify discovers the egress address and checks it against the allowlist,
+// returning an *EgressIpError when it is not allowed.
+func (c EgressCheck) Verify(ctx context.Context, client Client) (netip.Addr, error) {
+	ip, err := c.EgressIp(ctx, client)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	allowed, err := c.allows(ip)
+	if err != nil {
+		return ip, err
+	}
+	if !allowed {
+		return ip, &EgressIpError{Ip: ip, Allowlist: c.Allowlist}
+	}
+	return ip, nil
+}
+
+func (c EgressCheck) allows(ip netip.Addr) (bool, error) {
+	for _, entry := range c.Allowlist {
+		entry = strings.TrimSpace(entry)
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return false, fmt.Errorf("invalid allowlist entry: %s - %w", entry, err)
+			}
+			if prefix.Contains(ip) {
+				return true, nil
+			}
+			continue
+		}
+
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return false, fmt.Errorf("invalid allowlist entry: %s - %w", entry, err)
+		}
+		if addr.Unmap() == ip {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func parseEchoedIp(body []byte) (netip.Addr, error) {
+	text := strings.TrimSpace(string(body))
+
+	var response struct {
+		Ip string `json:"ip"`
+	}
+	if strings.HasPrefix(text, "{") {
+		if err := json.Unmarshal([]byte(text), &response); err != nil {
+			return netip.Addr{}, fmt.Errorf("unable to parse egress echo response: %w", err)
+		}
+		text = response.Ip
+	}
+
+	ip, err := netip.ParseAddr(text)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("egress echo response is not an ip: %q", text)
+	}
+	return ip.Unmap(), nil
+}
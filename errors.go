@@ -0,0 +1,113 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ErrSigning is matched by a *SigningError.
+var ErrSigning = errors.New("signing failed")
+
+// SigningError is returned, within the *ApiError, when a HeaderFunc fails to
+// sign a request, which is then not sent.
+type SigningError struct {
+	Err error
+}
+
+func (e *SigningError) Error() string {
+	return "signing request: " + e.Err.Error()
+}
+
+func (e *SigningError) Unwrap() error        { return e.Err }
+func (e *SigningError) Is(target error) bool { return target == ErrSigning }
+
+// ErrInvalidUrl is wrapped by the error returned when a call's URL does not
+// parse.
+var ErrInvalidUrl = errors.New("invalid URL")
+
+// isLocalError reports whether err was raised before the request was sent
+// and would be raised again by another attempt, so retrying it, or counting
+// it against the API, is pointless.
+func isLocalError(err error) bool {
+	return errors.Is(err, ErrSigning) ||
+		errors.Is(err, ErrSignatureExpired) ||
+		errors.Is(err, ErrSignedRequestModified) ||
+		errors.Is(err, ErrInvalidUrl) ||
+		errors.Is(err, ErrCircuitOpen)
+}
+
+// Sentinels matched with errors.Is by the typed errors below. ErrRateLimited
+// also matches a 429 from the API.
+var (
+	ErrAuthentication = errors.New("authentication failed")
+	ErrNotFound       = errors.New("not found")
+	ErrValidation     = errors.New("invalid request")
+	ErrServer         = errors.New("server error")
+)
+
+// RateLimitError is returned for a 429. RetryAfter says how long the API
+// asked to wait, when it did.
+type RateLimitError struct{ *ApiError }
+
+// AuthenticationError is returned for a 401 or 403.
+type AuthenticationError struct{ *ApiError }
+
+// NotFoundError is returned for a 404.
+type NotFoundError struct{ *ApiError }
+
+// ValidationError is returned for a 400 or 422.
+type ValidationError struct{ *ApiError }
+
+// ServerError is returned for a 5xx.
+type ServerError struct{ *ApiError }
+
+func (e *RateLimitError) Unwrap() error      { return e.ApiError }
+func (e *AuthenticationError) Unwrap() error { return e.ApiError }
+func (e *NotFoundError) Unwrap() error       { return e.ApiError }
+func (e *ValidationError) Unwrap() error     { return e.ApiError }
+func (e *ServerError) Unwrap() error         { return e.ApiError }
+
+func (e *RateLimitError) Is(target error) bool      { return target == ErrRateLimited }
+func (e *AuthenticationError) Is(target error) bool { return target == ErrAuthentication }
+func (e *NotFoundError) Is(target error) bool       { return target == ErrNotFound }
+func (e *ValidationError) Is(target error) bool     { return target == ErrValidation }
+func (e *ServerError) Is(target error) bool         { return target == ErrServer }
+
+// typedError wraps err in the error type for its status code. Other errors,
+// including failures without a response, are returned as the *ApiError.
+func typedError(err *ApiError) error {
+	if err == nil {
+		return nil
+	}
+
+	switch code := err.CodeReceived; {
+	case code == http.StatusTooManyRequests:
+		return &RateLimitError{err}
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		return &AuthenticationError{err}
+	case code == http.StatusNotFound:
+		return &NotFoundError{err}
+	case code == http.StatusBadRequest || code == http.StatusUnprocessableEntity:
+		return &ValidationError{err}
+	case code >= http.StatusInternalServerError:
+		return &ServerError{err}
+	default:
+		return err
+	}
+}
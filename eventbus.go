@@ -0,0 +1,121 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Event is a normalized message published on an EventBus, whether it came
+// from a websocket channel or a REST poller.
+type Event struct {
+	Topic   string
+	Source  string
+	Time    time.Time
+	Payload interface{}
+}
+
+// EventBus fans published events out to subscribers by topic, so websocket
+// feeds and REST pollers covering the same data can be consumed through one
+// API. Publishing never blocks: events for a subscriber whose buffer is full
+// are dropped and counted.
+type EventBus struct {
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+}
+
+// Subscription receives events for its topics on C.
+type Subscription struct {
+	C <-chan Event
+
+	c       chan Event
+	topics  map[string]bool
+	dropped atomic.Uint64
+	bus     *EventBus
+	once    sync.Once
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{subs: map[*Subscription]struct{}{}}
+}
+
+// Subscribe returns a subscription buffering up to buffer events for topics,
+// or for every topic when none are given.
+func (b *EventBus) Subscribe(buffer int, topics ...string) *Subscription {
+	c := make(chan Event, buffer)
+	s := &Subscription{C: c, c: c, bus: b}
+	if len(topics) > 0 {
+		s.topics = make(map[string]bool, len(topics))
+		for _, t := range topics {
+			s.topics[t] = true
+		}
+	}
+
+	b.mu.Lock()
+	b.subs[s] = struct{}{}
+	b.mu.Unlock()
+	return s
+}
+
+// Publish delivers event to every matching subscriber. Time defaults to now.
+func (b *EventBus) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for s := range b.subs {
+		if s.topics != nil && !s.topics[event.Topic] {
+			continue
+		}
+		select {
+		case s.c <- event:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+}
+
+// Close stops delivery and closes C.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.bus.mu.Lock()
+		delete(s.bus.subs, s)
+		s.bus.mu.Unlock()
+		close(s.c)
+	})
+}
+
+// Dropped returns how many events were dropped because C was full.
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// PublishLongPoll runs poller until ctx is done, publishing each item as an
+// event on topic with source "rest".
+func PublishLongPoll[T any](ctx context.Context, bus *EventBus, topic string, poller *LongPoller[T]) {
+	for batch := range poller.Run(ctx) {
+		for _, item := range batch.Items {
+			bus.Publish(Event{Topic: topic, Source: "rest", Payload: item})
+		}
+	}
+}
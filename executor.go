@@ -0,0 +1,43 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import "net/http"
+
+// Executor sends a fully built and signed request, in place of the client's
+// http.Client, e.g. to route orders through an internal gateway. Core still
+// marshals the request, signs it, reads the body, and maps errors. A response
+// must be returned with its body unread; the caller closes it.
+type Executor interface {
+	Execute(req *http.Request) (*http.Response, error)
+}
+
+// ExecutorFunc adapts a function to an Executor.
+type ExecutorFunc func(req *http.Request) (*http.Response, error)
+
+func (f ExecutorFunc) Execute(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// execute sends req through the configured Executor, or through the client's
+// http.Client with a retry on a stale connection.
+func execute(config *Config, client Client, req *http.Request) (*http.Response, error) {
+	if config.Executor != nil {
+		return config.Executor.Execute(req)
+	}
+	return doRequest(client.HttpClient(), req)
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var ErrSignatureExpired = errors.New("request signature expired before send")
+
+// SignatureExpiry bounds how long a signed request may wait before it is sent.
+type SignatureExpiry struct {
+	// MaxAge is the longest a request may sit between signing and sending.
+	// Older requests fail locally with ErrSignatureExpired rather than being
+	// sent and rejected with a 401. The age is checked last, after
+	// GuardSignedRequests and RequestCompression, immediately before the request is handed to
+	// the transport; time spent waiting for a connection is not covered.
+	// Zero disables the check.
+	MaxAge time.Duration
+
+	// Header, when set, carries the unix time after which the server should
+	// reject the request, for APIs that accept a client-supplied expiry. It
+	// is set before the HeaderFunc runs so signatures can cover it.
+	Header string
+
+	// Window is added to the signing time to compute the Header value, or
+	// the call's context deadline is used if that is sooner. Defaults to
+	// MaxAge.
+	Window time.Duration
+}
+
+func (e *SignatureExpiry) apply(req *http.Request, signedAt time.Time) {
+	if e.Header == "" {
+		return
+	}
+
+	window := e.Window
+	if window <= 0 {
+		window = e.MaxAge
+	}
+	expiresAt := signedAt.Add(window)
+	if deadline, ok := req.Context().Deadline(); ok && deadline.Before(expiresAt) {
+		expiresAt = deadline
+	}
+	req.Header.Set(e.Header, strconv.FormatInt(unixCeil(expiresAt), 10))
+}
+
+func (e *SignatureExpiry) check(signedAt, now time.Time) error {
+	if e.MaxAge <= 0 {
+		return nil
+	}
+	if age := now.Sub(signedAt); age > e.MaxAge {
+		return fmt.Errorf("%w: signed %s ago, max age %s", ErrSignatureExpired, age, e.MaxAge)
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ReadFallback sends reads to a secondary base URL, such as a mirror or an
+// internal cache service, once the primary has failed repeatedly. While
+// degraded, one read per ProbeInterval is tried against the primary, and the
+// first success restores it. Time is read from the client's Config.Clock.
+type ReadFallback struct {
+	// BaseUrl is the secondary base URL.
+	BaseUrl string
+
+	// PathPrefixes limits the fallback to paths with one of these prefixes.
+	// Empty means every GET.
+	PathPrefixes []string
+
+	// FailureThreshold is the number of consecutive primary failures, 5xx or
+	// transport errors, that switch reads to the secondary. Defaults to 3.
+	FailureThreshold int
+
+	// ProbeInterval is how often the primary is retried while degraded.
+	// Defaults to 30 seconds.
+	ProbeInterval time.Duration
+
+	// OnChange, when set, is called when reads switch to or from the
+	// secondary.
+	OnChange func(degraded bool)
+
+	// HeaderFunc sets the headers of reads sent to the secondary. The call's
+	// own HeaderFunc is never used there, so the primary's credentials do
+	// not reach another host. Defaults to sending no credentials.
+	HeaderFunc HeaderFunc
+
+	mu        sync.Mutex
+	failures  int
+	degraded  bool
+	lastProbe time.Time
+}
+
+// Degraded reports whether reads are currently sent to the secondary.
+func (f *ReadFallback) Degraded() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.degraded
+}
+
+func (f *ReadFallback) wrap(next CallFunc) CallFunc {
+	if f == nil {
+		return next
+	}
+
+	return func(ctx context.Context, request *ApiRequest, headersFunc HeaderFunc) *ApiResponse {
+		if !f.applies(request) {
+			return next(ctx, request, headersFunc)
+		}
+
+		config := configOf(request.Client)
+		if !f.usePrimary(config.now()) {
+			return next(ctx, f.secondary(request), f.headerFunc())
+		}
+
+		response := next(ctx, request, headersFunc)
+		if !isPrimaryFailure(response) {
+			f.recordSuccess()
+			return response
+		}
+
+		if f.recordFailure(config.now()) {
+			response.release()
+			return next(ctx, f.secondary(request), f.headerFunc())
+		}
+		return response
+	}
+}
+
+func (f *ReadFallback) applies(request *ApiRequest) bool {
+	if request.HttpMethod != http.MethodGet || f.BaseUrl == "" {
+		return false
+	}
+	if len(f.PathPrefixes) == 0 {
+		return true
+	}
+	for _, prefix := range f.PathPrefixes {
+		if strings.HasPrefix(request.Path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// usePrimary reports whether this call should go to the primary, either
+// because it is healthy or because a probe is due as of now.
+func (f *ReadFallback) usePrimary(now time.Time) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.degraded {
+		return true
+	}
+
+	interval := f.ProbeInterval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	if now.Sub(f.lastProbe) < interval {
+		return false
+	}
+	f.lastProbe = now
+	return true
+}
+
+func (f *ReadFallback) recordSuccess() {
+	f.mu.Lock()
+	recovered := f.degraded
+	f.failures = 0
+	f.degraded = false
+	f.mu.Unlock()
+
+	if recovered && f.OnChange != nil {
+		f.OnChange(false)
+	}
+}
+
+// recordFailure counts a primary failure and reports whether the call should
+// be answered by the secondary.
+func (f *ReadFallback) recordFailure(now time.Time) bool {
+	threshold := f.FailureThreshold
+	if threshold <= 0 {
+		threshold = 3
+	}
+
+	f.mu.Lock()
+	f.failures++
+	wasDegraded := f.degraded
+	if f.failures >= threshold && !f.degraded {
+		f.degraded = true
+		f.lastProbe = now
+	}
+	degraded := f.degraded
+	f.mu.Unlock()
+
+	if degraded && !wasDegraded && f.OnChange != nil {
+		f.OnChange(true)
+	}
+	return degraded
+}
+
+func (f *ReadFallback) headerFunc() HeaderFunc {
+	if f.HeaderFunc == nil {
+		return func(*http.Request, string, []byte, Client, time.Time) {}
+	}
+	return f.HeaderFunc
+}
+
+func (f *ReadFallback) secondary(request *ApiRequest) *ApiRequest {
+	redirected := *request
+	redirected.baseUrl = f.BaseUrl
+	return &redirected
+}
+
+// isPrimaryFailure reports whether response shows the server failing. Local
+// failures, such as cancellation, signing, or an open circuit, do not count.
+func isPrimaryFailure(response *ApiResponse) bool {
+	err := response.err
+	if err == nil {
+		return false
+	}
+	if response.statusCode == 0 {
+		return CancelReasonOf(err) == CancelReasonNone && !isLocalError(err)
+	}
+	return response.statusCode >= http.StatusInternalServerError
+}
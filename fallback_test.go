@@ -0,0 +1,88 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReadFallbackDoesNotSendCallerCredentialsToSecondary(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer primary.Close()
+
+	var received http.Header
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		w.Write([]byte(`{}`))
+	}))
+	defer secondary.Close()
+
+	credentials := &Credentials{AccessKey: "access-key", Passphrase: "passphrase", SigningKey: testHmacSecret}
+	for _, tt := range []struct {
+		name       string
+		headerFunc HeaderFunc
+		want       http.Header
+	}{
+		{name: "default", want: http.Header{}},
+		{
+			name: "secondary HeaderFunc",
+			headerFunc: func(req *http.Request, path string, body []byte, client Client, t time.Time) {
+				req.Header.Set("X-Mirror-Token", "mirror")
+			},
+			want: http.Header{"X-Mirror-Token": {"mirror"}},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(primary.URL, nil)
+			client.Config().ReadFallback = &ReadFallback{BaseUrl: secondary.URL, FailureThreshold: 1, HeaderFunc: tt.headerFunc}
+
+			received = nil
+			if err := HttpGet(context.Background(), client, "/products", EmptyQueryParams, nil, nil, HmacSignatureHeaderFunc(credentials)); err != nil {
+				t.Fatal(err)
+			}
+			if received == nil {
+				t.Fatal("read was not sent to the secondary")
+			}
+			for name := range received {
+				if _, ok := tt.want[name]; !ok && isCallerHeader(name) {
+					t.Errorf("secondary received %s", name)
+				}
+			}
+			for name, want := range tt.want {
+				if got := received.Get(name); got != want[0] {
+					t.Errorf("%s = %q, want %q", name, got, want[0])
+				}
+			}
+		})
+	}
+}
+
+// isCallerHeader reports whether name could have been set by a HeaderFunc
+// rather than by net/http.
+func isCallerHeader(name string) bool {
+	switch name {
+	case "Accept-Encoding", "User-Agent":
+		return false
+	}
+	return true
+}
@@ -0,0 +1,42 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/coinbase-samples/core-go/testutil"
+)
+
+func TestInjectedResponsesCarryAFullStatusLine(t *testing.T) {
+	transports := map[string]http.RoundTripper{
+		"fault injector":      testutil.NewFaultInjector(nil, testutil.Faults{ServerErrorRate: 1, Seed: 1}),
+		"recording transport": testutil.NewRecordingTransport(http.StatusServiceUnavailable, `{}`),
+	}
+	for name, transport := range transports {
+		req, _ := http.NewRequest(http.MethodGet, "https://api.example.com/time", nil)
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		res.Body.Close()
+		if res.Status != "503 Service Unavailable" {
+			t.Fatalf("%s: Status = %q, want %q", name, res.Status, "503 Service Unavailable")
+		}
+	}
+}
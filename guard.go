@@ -0,0 +1,76 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// ErrSignedRequestModified is wrapped by the *SigningError returned when
+// Config.GuardSignedRequests finds a request changed after it was signed.
+var ErrSignedRequestModified = errors.New("signed request was modified")
+
+// verifySignedRequest reports an error when the method, URL, or body of req
+// no longer match what the HeaderFunc was given to sign, e.g. because a
+// HeaderFunc later in a chain changed them. When RequestCompression gzipped
+// the body, compressed is true and the body is compared after decompressing
+// it, since the signature covers the uncompressed bytes.
+func verifySignedRequest(req *http.Request, method string, signedUrl *url.URL, body []byte, compressed bool) error {
+	switch {
+	case req.Method != method:
+		return fmt.Errorf("%w: method changed from %s to %s", ErrSignedRequestModified, method, req.Method)
+	case req.URL.Host != signedUrl.Host || req.URL.Path != signedUrl.Path:
+		return fmt.Errorf("%w: URL changed from %s to %s", ErrSignedRequestModified, signedUrl.Redacted(), req.URL.Redacted())
+	case req.URL.RawQuery != signedUrl.RawQuery:
+		return fmt.Errorf("%w: query changed", ErrSignedRequestModified)
+	}
+
+	var sent []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		if sent, err = io.ReadAll(req.Body); err != nil {
+			return err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(sent))
+	}
+	if compressed {
+		var err error
+		if sent, err = gunzip(sent); err != nil {
+			return fmt.Errorf("%w: body is not valid gzip: %v", ErrSignedRequestModified, err)
+		}
+	}
+	if !bytes.Equal(sent, body) {
+		return fmt.Errorf("%w: body changed", ErrSignedRequestModified)
+	}
+	return nil
+}
+
+func gunzip(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
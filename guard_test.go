@@ -0,0 +1,52 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestVerifySignedRequestComparesUncompressedBody(t *testing.T) {
+	signedUrl, _ := url.Parse("https://api.example.com/orders")
+	body := bytes.Repeat([]byte(`{"size":"1"}`), 200)
+	compression := &RequestCompression{Assume: true}
+
+	newRequest := func() *http.Request {
+		req, _ := http.NewRequest(http.MethodPost, signedUrl.String(), bytes.NewReader(body))
+		return req
+	}
+
+	req := newRequest()
+	compressed, err := compression.compress(context.Background(), req, body, nil, nil)
+	if err != nil || !compressed {
+		t.Fatalf("compress = %v, %v; want true, nil", compressed, err)
+	}
+	if err := verifySignedRequest(req, http.MethodPost, signedUrl, body, compressed); err != nil {
+		t.Fatalf("compressed body of the signed bytes rejected: %v", err)
+	}
+
+	req = newRequest()
+	compression.compress(context.Background(), req, append([]byte(`{}`), body...), nil, nil)
+	if err := verifySignedRequest(req, http.MethodPost, signedUrl, body, true); !errors.Is(err, ErrSignedRequestModified) {
+		t.Fatalf("compressed body of different bytes = %v, want ErrSignedRequestModified", err)
+	}
+}
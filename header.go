@@ -0,0 +1,176 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// CorrelationIdHeader is the default header set by CorrelationIdHeaderFunc.
+const CorrelationIdHeader = "X-Correlation-Id"
+
+// HeaderProvider sets a request's headers like a HeaderFunc, but can fail,
+// e.g. on a malformed key or an unavailable KMS. An error aborts the call
+// with a *SigningError.
+type HeaderProvider interface {
+	Apply(req *http.Request, path string, body []byte, client Client, t time.Time) error
+}
+
+// HeaderProviderFunc adapts a function to a HeaderProvider.
+type HeaderProviderFunc func(req *http.Request, path string, body []byte, client Client, t time.Time) error
+
+func (f HeaderProviderFunc) Apply(req *http.Request, path string, body []byte, client Client, t time.Time) error {
+	return f(req, path, body, client, t)
+}
+
+// ProviderHeaderFunc adapts provider to a HeaderFunc for the calls that take
+// one. Errors from Apply are passed to FailSigning.
+func ProviderHeaderFunc(provider HeaderProvider) HeaderFunc {
+	return func(req *http.Request, path string, body []byte, client Client, t time.Time) {
+		if err := provider.Apply(req, path, body, client, t); err != nil {
+			FailSigning(req, err)
+		}
+	}
+}
+
+type signingFailureKey struct{}
+
+type signingFailure struct {
+	err error
+}
+
+// FailSigning aborts the call req belongs to with a *SigningError wrapping
+// err instead of sending it. It is for HeaderFuncs, and has no effect on
+// requests not made by a call. The first failure is kept.
+func FailSigning(req *http.Request, err error) {
+	if failure, ok := req.Context().Value(signingFailureKey{}).(*signingFailure); ok && failure.err == nil {
+		failure.err = err
+	}
+}
+
+func withSigningFailure(ctx context.Context) (context.Context, *signingFailure) {
+	failure := &signingFailure{}
+	return context.WithValue(ctx, signingFailureKey{}, failure), failure
+}
+
+// ChainHeaderFuncs returns a HeaderFunc calling each of headerFuncs in
+// order, skipping nil ones. Put the signature last so it covers headers set
+// before it.
+func ChainHeaderFuncs(headerFuncs ...HeaderFunc) HeaderFunc {
+	return func(req *http.Request, path string, body []byte, client Client, t time.Time) {
+		for _, headerFunc := range headerFuncs {
+			if headerFunc != nil {
+				headerFunc(req, path, body, client, t)
+			}
+		}
+	}
+}
+
+// StaticHeaderFunc returns a HeaderFunc setting name to value.
+func StaticHeaderFunc(name, value string) HeaderFunc {
+	return func(req *http.Request, path string, body []byte, client Client, t time.Time) {
+		req.Header.Set(name, value)
+	}
+}
+
+// UserAgentHeaderFunc returns a HeaderFunc setting the User-Agent, e.g. to
+// "prime-sdk-go/1.2.0 core-go/" + Version.
+func UserAgentHeaderFunc(userAgent string) HeaderFunc {
+	return StaticHeaderFunc("User-Agent", userAgent)
+}
+
+// PortfolioHeaderFunc returns a HeaderFunc setting header, the one a
+// product's API uses to scope a call, to portfolioId.
+func PortfolioHeaderFunc(header, portfolioId string) HeaderFunc {
+	return StaticHeaderFunc(header, portfolioId)
+}
+
+type correlationIdKey struct{}
+
+// WithCorrelationId returns a context whose calls send id through
+// CorrelationIdHeaderFunc, keeping it the same across retries.
+func WithCorrelationId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIdKey{}, id)
+}
+
+// CorrelationIdFromContext returns the id set by WithCorrelationId, or "".
+func CorrelationIdFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIdKey{}).(string)
+	return id
+}
+
+// CorrelationIdHeaderFunc returns a HeaderFunc setting header, or
+// CorrelationIdHeader when empty, to the id from WithCorrelationId or else a
+// random UUID.
+func CorrelationIdHeaderFunc(header string) HeaderFunc {
+	if header == "" {
+		header = CorrelationIdHeader
+	}
+	return func(req *http.Request, path string, body []byte, client Client, t time.Time) {
+		id := CorrelationIdFromContext(req.Context())
+		if id == "" {
+			id = newUuid()
+		}
+		req.Header.Set(header, id)
+	}
+}
+
+// SignatureHeaderFunc returns a HeaderFunc signing with the signer for
+// credentials, as NewSigner picks it.
+func SignatureHeaderFunc(credentials *Credentials) (HeaderFunc, error) {
+	signer, err := NewSigner(credentials)
+	if err != nil {
+		return nil, err
+	}
+	return SignerHeaderFunc(signer), nil
+}
+
+// DialHeaders returns a function generating the headers for a websocket
+// handshake to rawUrl, by running headersFunc over a GET of rawUrl at the
+// time of the call, read from client's Config.Clock. Call it on every dial,
+// including redials by a reconnect loop minutes later, so a handshake never
+// carries a signature that has already expired. Pass a HeaderProvider
+// through ProviderHeaderFunc. Signing failures are returned as a
+// *SigningError.
+func DialHeaders(client Client, rawUrl string, headersFunc HeaderFunc) func(ctx context.Context) (http.Header, error) {
+	return func(ctx context.Context) (http.Header, error) {
+		ctx, signing := withSigningFailure(ctx)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawUrl, nil)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidUrl, err)
+		}
+
+		headersFunc(req, req.URL.Path, nil, client, configOf(client).now())
+		if signing.err != nil {
+			return nil, &SigningError{Err: signing.err}
+		}
+		return req.Header, nil
+	}
+}
+
+// newUuid returns a random version 4 UUID.
+func newUuid() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,69 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// steppingClock advances by a minute on every read.
+type steppingClock struct {
+	now time.Time
+}
+
+func (c *steppingClock) Now() time.Time {
+	c.now = c.now.Add(time.Minute)
+	return c.now
+}
+
+func TestDialHeadersSignsAtEachDial(t *testing.T) {
+	client := NewClient("https://api.example.com", nil)
+	client.Config().Clock = &steppingClock{now: time.Unix(1700000000, 0)}
+	credentials := &Credentials{AccessKey: "access-key", Passphrase: "passphrase", SigningKey: testHmacSecret}
+	headers := DialHeaders(client, "wss://ws-feed.example.com/?channel=user", HmacSignatureHeaderFunc(credentials))
+
+	first, err := headers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := headers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.Get("CB-ACCESS-KEY") != "access-key" || first.Get("CB-ACCESS-TIMESTAMP") != "1700000060" {
+		t.Fatalf("first dial headers = %v, want signed at the clock's time", first)
+	}
+	if second.Get("CB-ACCESS-TIMESTAMP") != "1700000120" || second.Get("CB-ACCESS-SIGN") == first.Get("CB-ACCESS-SIGN") {
+		t.Fatalf("redial headers = %v, want a fresh signature", second)
+	}
+}
+
+func TestDialHeadersReportsSigningFailure(t *testing.T) {
+	failure := errors.New("kms unavailable")
+	headers := DialHeaders(nil, "wss://ws-feed.example.com/", ProviderHeaderFunc(HeaderProviderFunc(
+		func(*http.Request, string, []byte, Client, time.Time) error { return failure })))
+
+	_, err := headers(context.Background())
+	if !errors.Is(err, ErrSigning) || !errors.Is(err, failure) {
+		t.Fatalf("err = %v, want a *SigningError wrapping the provider's error", err)
+	}
+}
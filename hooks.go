@@ -0,0 +1,156 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
+	"sync"
+	"time"
+)
+
+// Hooks are optional callbacks into each call made by a client. Nil hooks
+// are skipped.
+//
+// The connection hooks are driven by net/http/httptrace and are useful for
+// finding where long tail latency is spent. Callers can also attach their own
+// httptrace.ClientTrace to a call's context; both are invoked.
+type Hooks struct {
+	// DnsDone reports a host lookup and how long it took.
+	DnsDone func(ctx context.Context, request *ApiRequest, host string, elapsed time.Duration, err error)
+
+	// ConnectDone reports a dial to addr and how long it took.
+	ConnectDone func(ctx context.Context, request *ApiRequest, addr string, elapsed time.Duration, err error)
+
+	// TlsHandshakeDone reports a TLS handshake and how long it took.
+	TlsHandshakeDone func(ctx context.Context, request *ApiRequest, elapsed time.Duration, err error)
+
+	// GotConn reports the connection used, and whether it was reused from
+	// the pool.
+	GotConn func(ctx context.Context, request *ApiRequest, reused bool)
+
+	// FirstByte reports the time from sending the request to the first
+	// byte of the response.
+	FirstByte func(ctx context.Context, request *ApiRequest, elapsed time.Duration)
+
+	// Informational reports each 1xx response received before the final
+	// one, such as 103 Early Hints with its Link headers. These responses
+	// are otherwise skipped and never treated as the call's status.
+	Informational func(ctx context.Context, request *ApiRequest, statusCode int, header http.Header)
+
+	// RequestCompressed reports the size of a request body before and
+	// after it was compressed.
+	RequestCompressed func(ctx context.Context, request *ApiRequest, uncompressed, compressed int)
+
+	// UnknownFields reports JSON response fields the response type does not
+	// declare, by path template. With Config.UnknownFields set, only fields
+	// not already in the report are passed, so each is reported once.
+	UnknownFields func(ctx context.Context, path string, fields []string)
+}
+
+func (h *Hooks) tracesConnections() bool {
+	return h.DnsDone != nil || h.ConnectDone != nil || h.TlsHandshakeDone != nil || h.GotConn != nil || h.FirstByte != nil ||
+		h.Informational != nil
+}
+
+// withClientTrace attaches a ClientTrace feeding the connection hooks to ctx.
+func (h *Hooks) withClientTrace(ctx context.Context, request *ApiRequest) context.Context {
+	if h == nil || !h.tracesConnections() {
+		return ctx
+	}
+
+	var (
+		mu       sync.Mutex
+		dnsStart time.Time
+		dnsHost  string
+		tlsStart time.Time
+		dials    = map[string]time.Time{}
+		wrote    time.Time
+	)
+
+	elapsedSince := func(start *time.Time) time.Duration {
+		mu.Lock()
+		defer mu.Unlock()
+		if start.IsZero() {
+			return 0
+		}
+		return time.Since(*start)
+	}
+	mark := func(t *time.Time) {
+		mu.Lock()
+		*t = time.Now()
+		mu.Unlock()
+	}
+
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(info httptrace.DNSStartInfo) {
+			mu.Lock()
+			dnsStart, dnsHost = time.Now(), info.Host
+			mu.Unlock()
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			if h.DnsDone != nil {
+				mu.Lock()
+				host := dnsHost
+				mu.Unlock()
+				h.DnsDone(ctx, request, host, elapsedSince(&dnsStart), info.Err)
+			}
+		},
+		ConnectStart: func(network, addr string) {
+			mu.Lock()
+			dials[addr] = time.Now()
+			mu.Unlock()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			mu.Lock()
+			start := dials[addr]
+			delete(dials, addr)
+			mu.Unlock()
+			if h.ConnectDone != nil {
+				h.ConnectDone(ctx, request, addr, time.Since(start), err)
+			}
+		},
+		TLSHandshakeStart: func() { mark(&tlsStart) },
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			if h.TlsHandshakeDone != nil {
+				h.TlsHandshakeDone(ctx, request, elapsedSince(&tlsStart), err)
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			if h.GotConn != nil {
+				h.GotConn(ctx, request, info.Reused)
+			}
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) { mark(&wrote) },
+		GotFirstResponseByte: func() {
+			if h.FirstByte != nil {
+				h.FirstByte(ctx, request, elapsedSince(&wrote))
+			}
+		},
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			if h.Informational != nil {
+				h.Informational(ctx, request, code, http.Header(header).Clone())
+			}
+			return nil
+		},
+	}
+
+	return httptrace.WithClientTrace(ctx, trace)
+}
@@ -0,0 +1,356 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+type idempotencyKey struct{}
+
+// WithIdempotencyKey returns a context whose call is submitted at most once
+// per key by an IdempotentSubmitter, e.g. keyed by a client order id.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+// IdempotencyKeyFromContext returns the key set by WithIdempotencyKey, or "".
+func IdempotencyKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKey{}).(string)
+	return key
+}
+
+// IdempotencyRecord is the recorded outcome of a submission.
+type IdempotencyRecord struct {
+	StatusCode   int         `json:"status_code"`
+	Header       http.Header `json:"header,omitempty"`
+	Body         []byte      `json:"body,omitempty"`
+	ErrorMessage string      `json:"error_message,omitempty"`
+	CodeExpected []int       `json:"code_expected,omitempty"`
+	ExpiresAt    time.Time   `json:"expires_at"`
+}
+
+// IdempotencyStore records submission outcomes by key. Get returns nil for a
+// missing or expired key.
+type IdempotencyStore interface {
+	Get(key string) (*IdempotencyRecord, error)
+	Put(key string, record *IdempotencyRecord) error
+}
+
+// IdempotentSubmitter replays the recorded outcome of calls whose
+// idempotency key was already submitted, so a submission retried after a
+// restart is not sent twice. Only definitive outcomes are recorded: a
+// success or a 4xx other than 429. A call that failed in transit may or may
+// not have been processed, and is left for the caller to reconcile. Add its
+// Middleware to the client.
+type IdempotentSubmitter struct {
+	Store IdempotencyStore
+
+	// Ttl is how long an outcome is kept. Defaults to 24 hours.
+	Ttl time.Duration
+
+	// Header, when set, also sends the key in this request header.
+	Header string
+
+	// OnReplay, when set, is called when a recorded outcome is returned.
+	OnReplay func(request *ApiRequest, key string)
+
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+type keyLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// Middleware returns the Middleware that records and replays outcomes for
+// calls made with WithIdempotencyKey. Other calls pass through.
+func (s *IdempotentSubmitter) Middleware() Middleware {
+	return func(next CallFunc) CallFunc {
+		return func(ctx context.Context, request *ApiRequest, headersFunc HeaderFunc) *ApiResponse {
+			key := IdempotencyKeyFromContext(ctx)
+			if key == "" {
+				return next(ctx, request, headersFunc)
+			}
+
+			// Concurrent submissions with one key are serialized so the
+			// second sees the first's outcome.
+			unlock := s.lock(key)
+			defer unlock()
+
+			record, err := s.Store.Get(key)
+			if err != nil {
+				return NewApiErrorResponse(request, &ApiError{
+					Message:   fmt.Sprintf("reading idempotency record %s: %v", key, err),
+					ParsedUrl: request.url(),
+					Err:       err,
+				})
+			}
+			if record != nil {
+				if s.OnReplay != nil {
+					s.OnReplay(request, key)
+				}
+				return record.replay(request)
+			}
+
+			if s.Header != "" {
+				headersFunc = withIdempotencyHeader(s.Header, key, headersFunc)
+			}
+			response := next(ctx, request, headersFunc)
+			if !isDefinitiveOutcome(response) {
+				return response
+			}
+
+			record = &IdempotencyRecord{
+				StatusCode: response.statusCode,
+				Header:     response.header.Clone(),
+				Body:       bytes.Clone(response.body),
+				ExpiresAt:  time.Now().Add(s.ttl()),
+			}
+			if response.err != nil {
+				record.ErrorMessage = response.err.Message
+				record.CodeExpected = response.err.CodeExpected
+			}
+			if err := s.Store.Put(key, record); err != nil {
+				response.release()
+				return NewApiErrorResponse(request, &ApiError{
+					Message:   fmt.Sprintf("call completed but recording idempotency key %s failed: %v", key, err),
+					ParsedUrl: request.url(),
+					Err:       err,
+				})
+			}
+			return response
+		}
+	}
+}
+
+func (s *IdempotentSubmitter) lock(key string) (unlock func()) {
+	s.mu.Lock()
+	if s.locks == nil {
+		s.locks = map[string]*keyLock{}
+	}
+	l := s.locks[key]
+	if l == nil {
+		l = &keyLock{}
+		s.locks[key] = l
+	}
+	l.refs++
+	s.mu.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if l.refs--; l.refs == 0 {
+			delete(s.locks, key)
+		}
+	}
+}
+
+func (s *IdempotentSubmitter) ttl() time.Duration {
+	if s.Ttl <= 0 {
+		return 24 * time.Hour
+	}
+	return s.Ttl
+}
+
+func (r *IdempotencyRecord) replay(request *ApiRequest) *ApiResponse {
+	response := NewApiResponse(request, r.StatusCode, r.Header.Clone(), bytes.Clone(r.Body))
+	if r.ErrorMessage != "" || !isExpectedStatusCode(request, r.StatusCode) {
+		response.err = &ApiError{
+			Message:      r.ErrorMessage,
+			CodeExpected: r.CodeExpected,
+			CodeReceived: r.StatusCode,
+			ParsedUrl:    request.url(),
+		}
+	}
+	return response
+}
+
+func isDefinitiveOutcome(response *ApiResponse) bool {
+	code := response.statusCode
+	if response.err == nil {
+		return code != 0
+	}
+	return code >= http.StatusBadRequest && code < http.StatusInternalServerError && code != http.StatusTooManyRequests
+}
+
+func withIdempotencyHeader(header, key string, headersFunc HeaderFunc) HeaderFunc {
+	return func(req *http.Request, path string, body []byte, client Client, t time.Time) {
+		req.Header.Set(header, key)
+		headersFunc(req, path, body, client, t)
+	}
+}
+
+// MemoryIdempotencyStore keeps records in memory, dropping expired ones on
+// access and in a sweep at most once a minute. Records do not survive a
+// restart.
+type MemoryIdempotencyStore struct {
+	mu        sync.Mutex
+	records   map[string]*IdempotencyRecord
+	nextSweep time.Time
+}
+
+// idempotencySweepInterval spaces out MemoryIdempotencyStore's sweeps of
+// expired records, so a write does not scan every record.
+const idempotencySweepInterval = time.Minute
+
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{records: map[string]*IdempotencyRecord{}}
+}
+
+func (s *MemoryIdempotencyStore) Get(key string) (*IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[key]
+	if !ok {
+		return nil, nil
+	}
+	if !time.Now().Before(record.ExpiresAt) {
+		delete(s.records, key)
+		return nil, nil
+	}
+	copied := *record
+	return &copied, nil
+}
+
+func (s *MemoryIdempotencyStore) Put(key string, record *IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now := time.Now(); !now.Before(s.nextSweep) {
+		for k, r := range s.records {
+			if !now.Before(r.ExpiresAt) {
+				delete(s.records, k)
+			}
+		}
+		s.nextSweep = now.Add(idempotencySweepInterval)
+	}
+	copied := *record
+	s.records[key] = &copied
+	return nil
+}
+
+// FileIdempotencyStore keeps each record as a JSON file in a directory, so
+// outcomes survive a restart. Cleanup removes expired records.
+type FileIdempotencyStore struct {
+	dir string
+}
+
+// NewFileIdempotencyStore returns a store in dir, creating it when needed.
+func NewFileIdempotencyStore(dir string) (*FileIdempotencyStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileIdempotencyStore{dir: dir}, nil
+}
+
+func (s *FileIdempotencyStore) Get(key string) (*IdempotencyRecord, error) {
+	record, err := readIdempotencyRecord(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !time.Now().Before(record.ExpiresAt) {
+		return nil, nil
+	}
+	return record, nil
+}
+
+func (s *FileIdempotencyStore) Put(key string, record *IdempotencyRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".idempotency-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(key))
+}
+
+// Cleanup removes expired records and returns how many were removed.
+func (s *FileIdempotencyStore) Cleanup() (int, error) {
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	now := time.Now()
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(s.dir, f.Name())
+		record, err := readIdempotencyRecord(path)
+		if err != nil || now.Before(record.ExpiresAt) {
+			continue
+		}
+		if err := os.Remove(path); err == nil {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+func (s *FileIdempotencyStore) path(key string) string {
+	return filepath.Join(s.dir, url.PathEscape(key)+".json")
+}
+
+func readIdempotencyRecord(path string) (*IdempotencyRecord, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	record := &IdempotencyRecord{}
+	if err := json.Unmarshal(data, record); err != nil {
+		return nil, fmt.Errorf("parsing idempotency record %s: %w", path, err)
+	}
+	return record, nil
+}
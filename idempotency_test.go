@@ -0,0 +1,151 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingCall answers every call with 200 and counts the calls made.
+func countingCall(calls *atomic.Int32) CallFunc {
+	return func(ctx context.Context, request *ApiRequest, headersFunc HeaderFunc) *ApiResponse {
+		calls.Add(1)
+		return NewApiResponse(request, http.StatusOK, nil, []byte(`{"order_id":"1"}`))
+	}
+}
+
+func newOrderRequest() *ApiRequest {
+	return &ApiRequest{Path: "/orders", HttpMethod: http.MethodPost, Client: NewClient("https://api.example.com", nil)}
+}
+
+func TestIdempotentSubmitterReplaysAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	ctx := WithIdempotencyKey(context.Background(), "order-1")
+	var calls atomic.Int32
+
+	store, err := NewFileIdempotencyStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := &IdempotentSubmitter{Store: store}
+	first.Middleware()(countingCall(&calls))(ctx, newOrderRequest(), nil)
+
+	store, err = NewFileIdempotencyStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed := false
+	second := &IdempotentSubmitter{Store: store, OnReplay: func(*ApiRequest, string) { replayed = true }}
+	response := second.Middleware()(countingCall(&calls))(ctx, newOrderRequest(), nil)
+
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("API called %d times across the restart, want 1", n)
+	}
+	if !replayed || string(response.Body()) != `{"order_id":"1"}` {
+		t.Fatalf("response after restart = %s (replayed %v), want the recorded body", response.Body(), replayed)
+	}
+}
+
+func TestIdempotentSubmitterResubmitsAfterTtl(t *testing.T) {
+	s := &IdempotentSubmitter{Store: NewMemoryIdempotencyStore(), Ttl: 10 * time.Millisecond}
+	ctx := WithIdempotencyKey(context.Background(), "order-1")
+	var calls atomic.Int32
+	submit := s.Middleware()(countingCall(&calls))
+
+	submit(ctx, newOrderRequest(), nil)
+	submit(ctx, newOrderRequest(), nil)
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("API called %d times within the ttl, want 1", n)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	submit(ctx, newOrderRequest(), nil)
+	if n := calls.Load(); n != 2 {
+		t.Fatalf("API called %d times after the ttl, want 2", n)
+	}
+}
+
+func TestIdempotentSubmitterSerializesConcurrentSubmissions(t *testing.T) {
+	s := &IdempotentSubmitter{Store: NewMemoryIdempotencyStore()}
+	ctx := WithIdempotencyKey(context.Background(), "order-1")
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	next := func(ctx context.Context, request *ApiRequest, headersFunc HeaderFunc) *ApiResponse {
+		calls.Add(1)
+		<-release
+		return NewApiResponse(request, http.StatusOK, nil, []byte(`{}`))
+	}
+	submit := s.Middleware()(next)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			submit(ctx, newOrderRequest(), nil)
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("API called %d times for concurrent submissions of one key, want 1", n)
+	}
+}
+
+func TestFileIdempotencyStoreCleanup(t *testing.T) {
+	store, err := NewFileIdempotencyStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	store.Put("expired", &IdempotencyRecord{StatusCode: http.StatusOK, ExpiresAt: now.Add(-time.Minute)})
+	store.Put("live", &IdempotencyRecord{StatusCode: http.StatusOK, ExpiresAt: now.Add(time.Hour)})
+
+	removed, err := store.Cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Fatalf("Cleanup removed %d records, want 1", removed)
+	}
+	if record, err := store.Get("live"); err != nil || record == nil {
+		t.Fatalf("Get(live) after Cleanup = %v, %v; want the record", record, err)
+	}
+}
+
+func TestMemoryIdempotencyStoreSweepsPeriodically(t *testing.T) {
+	s := NewMemoryIdempotencyStore()
+	s.Put("first", &IdempotencyRecord{ExpiresAt: time.Now().Add(-time.Minute)})
+	s.Put("second", &IdempotencyRecord{ExpiresAt: time.Now().Add(time.Hour)})
+	if len(s.records) != 2 {
+		t.Fatalf("%d records after a second write within the sweep interval, want 2", len(s.records))
+	}
+
+	s.nextSweep = time.Now()
+	s.Put("third", &IdempotencyRecord{ExpiresAt: time.Now().Add(time.Hour)})
+	if _, ok := s.records["first"]; ok || len(s.records) != 2 {
+		t.Fatalf("records after a due sweep = %d (expired kept %v), want the expired one dropped", len(s.records), ok)
+	}
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// InFlightRequest describes a call that has started but not completed.
+type InFlightRequest struct {
+	Method  string
+	Path    string
+	Started time.Time
+	Elapsed time.Duration
+}
+
+type inFlightCall struct {
+	method  string
+	path    string
+	started time.Time
+	cancel  context.CancelCauseFunc
+}
+
+// InFlightTracker records outstanding calls and can abort them through their
+// contexts. It is safe for concurrent use.
+type InFlightTracker struct {
+	mu    sync.Mutex
+	next  uint64
+	calls map[uint64]*inFlightCall
+}
+
+func NewInFlightTracker() *InFlightTracker {
+	return &InFlightTracker{calls: make(map[uint64]*inFlightCall)}
+}
+
+// InFlight returns the outstanding calls, oldest first.
+func (t *InFlightTracker) InFlight() []InFlightRequest {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	requests := make([]InFlightRequest, 0, len(t.calls))
+	for _, c := range t.calls {
+		requests = append(requests, InFlightRequest{
+			Method:  c.method,
+			Path:    c.path,
+			Started: c.started,
+			Elapsed: now.Sub(c.started),
+		})
+	}
+
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].Started.Before(requests[j].Started)
+	})
+
+	return requests
+}
+
+// CancelAll cancels the context of every outstanding call, with
+// ErrClientShutdown as the cause, and returns how many were canceled. Calls
+// started afterwards are unaffected.
+func (t *InFlightTracker) CancelAll() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, c := range t.calls {
+		c.cancel(ErrClientShutdown)
+	}
+	return len(t.calls)
+}
+
+// track registers a call, across all of its attempts, and returns a context
+// that CancelAll can cancel, along with a function that must be called when
+// the call completes. A nil tracker tracks nothing.
+func (t *InFlightTracker) track(ctx context.Context, method, path string) (context.Context, func()) {
+	if t == nil {
+		return ctx, func() {}
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	t.mu.Lock()
+	t.next++
+	id := t.next
+	t.calls[id] = &inFlightCall{
+		method:  method,
+		path:    path,
+		started: time.Now(),
+		cancel:  cancel,
+	}
+	t.mu.Unlock()
+
+	return ctx, func() {
+		t.mu.Lock()
+		delete(t.calls, id)
+		t.mu.Unlock()
+		cancel(nil)
+	}
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCancelAllReachesCallWaitingToRetry(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, nil)
+	client.Config().Tracker = NewInFlightTracker()
+	client.Config().Retry = &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour, MaxDelay: time.Hour}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- HttpGet(context.Background(), client, "/orders", EmptyQueryParams, nil, nil, noHeaders)
+	}()
+
+	for deadline := time.Now().Add(5 * time.Second); attempts.Load() == 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("first attempt was not sent")
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	if inFlight := client.Config().InFlight(); len(inFlight) != 1 {
+		t.Fatalf("InFlight during backoff = %v, want the waiting call", inFlight)
+	}
+	if n := client.Config().CancelAll(); n != 1 {
+		t.Fatalf("CancelAll canceled %d calls, want 1", n)
+	}
+
+	select {
+	case err := <-done:
+		if reason := CancelReasonOf(err); reason != CancelReasonShutdown {
+			t.Fatalf("call ended with %v (reason %v), want a shutdown cancellation", err, reason)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("call kept waiting to retry after CancelAll")
+	}
+	if n := attempts.Load(); n != 1 {
+		t.Fatalf("%d attempts sent, want 1", n)
+	}
+}
@@ -0,0 +1,385 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// JwtSigner mints JWTs for Coinbase Developer Platform API keys, ES256 for EC
+// keys and EdDSA for Ed25519 keys. Signing only goes through crypto.Signer,
+// so the key can live in a KMS or HSM.
+type JwtSigner struct {
+	// KeyName is the API key name, e.g. "organizations/{org}/apiKeys/{id}".
+	KeyName string
+
+	// Ttl is how long a token is valid. Defaults to two minutes.
+	Ttl time.Duration
+
+	key crypto.Signer
+}
+
+// NewJwtSigner parses pemKey, an EC private key in SEC 1 or PKCS #8 PEM form.
+// Escaped newlines, as found in downloaded key files, are accepted.
+func NewJwtSigner(keyName, pemKey string) (*JwtSigner, error) {
+	block, _ := pem.Decode([]byte(strings.ReplaceAll(pemKey, `\n`, "\n")))
+	if block == nil {
+		got := (&Credentials{SigningKey: pemKey}).KeyFormat()
+		return nil, fmt.Errorf("jwt signing requires an %s in PEM form, got %s", KeyFormatEcPem, got)
+	}
+
+	key, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		parsed, pkcs8Err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if pkcs8Err != nil {
+			return nil, fmt.Errorf("unable to parse EC private key: %w", err)
+		}
+		var ok bool
+		if key, ok = parsed.(*ecdsa.PrivateKey); !ok {
+			return nil, fmt.Errorf("jwt signing key is %T, not an EC private key", parsed)
+		}
+	}
+
+	return NewJwtSignerFromKey(keyName, key)
+}
+
+// NewJwtSignerFromKey returns a signer using key, which may be backed by a
+// KMS or HSM so the private key never enters process memory. The key must be
+// a P-256 ECDSA or an Ed25519 key.
+func NewJwtSignerFromKey(keyName string, key crypto.Signer) (*JwtSigner, error) {
+	switch public := key.Public().(type) {
+	case ed25519.PublicKey:
+	case *ecdsa.PublicKey:
+		if public.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("ES256 requires a P-256 key, got %s", public.Curve.Params().Name)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported jwt signing key %T", public)
+	}
+	return &JwtSigner{KeyName: keyName, key: key}, nil
+}
+
+// NewEd25519JwtSigner parses base64Key, an Ed25519 private key or seed in
+// standard base64.
+func NewEd25519JwtSigner(keyName, base64Key string) (*JwtSigner, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(base64Key))
+	if err != nil {
+		got := (&Credentials{SigningKey: base64Key}).KeyFormat()
+		return nil, fmt.Errorf("ed25519 jwt signing requires a base64 %s, got %s: %w", KeyFormatEd25519, got, err)
+	}
+
+	switch len(raw) {
+	case ed25519.PrivateKeySize:
+		return &JwtSigner{KeyName: keyName, key: ed25519.PrivateKey(raw)}, nil
+	case ed25519.SeedSize:
+		return &JwtSigner{KeyName: keyName, key: ed25519.NewKeyFromSeed(raw)}, nil
+	default:
+		return nil, fmt.Errorf("ed25519 signing key is %d bytes, expected %d or %d", len(raw), ed25519.SeedSize, ed25519.PrivateKeySize)
+	}
+}
+
+// JwtUri returns the uri claim for a request, e.g.
+// "GET api.coinbase.com/api/v3/brokerage/accounts".
+func JwtUri(method, host, path string) string {
+	return method + " " + host + path
+}
+
+// Token mints a token for uri valid from t. An empty uri mints a token for
+// websocket authentication.
+func (s *JwtSigner) Token(uri string, t time.Time) (string, error) {
+	return s.token(uri, t, t.Add(s.ttl()))
+}
+
+// TokenUntil mints a token for uri valid from t until deadline, or for the
+// usual ttl if that ends sooner.
+func (s *JwtSigner) TokenUntil(uri string, t, deadline time.Time) (string, error) {
+	expiresAt := t.Add(s.ttl())
+	if deadline.Before(expiresAt) {
+		expiresAt = deadline
+	}
+	return s.token(uri, t, expiresAt)
+}
+
+func (s *JwtSigner) token(uri string, t, expiresAt time.Time) (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	header := map[string]string{
+		"alg":   s.algorithm(),
+		"typ":   "JWT",
+		"kid":   s.KeyName,
+		"nonce": hex.EncodeToString(nonce),
+	}
+	claims := map[string]interface{}{
+		"sub": s.KeyName,
+		"iss": "cdp",
+		"nbf": t.Unix(),
+		"exp": unixCeil(expiresAt),
+	}
+	if uri != "" {
+		claims["uri"] = uri
+	}
+
+	encodedHeader, err := encodeJwtPart(header)
+	if err != nil {
+		return "", err
+	}
+	encodedClaims, err := encodeJwtPart(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := encodedHeader + "." + encodedClaims
+	signature, err := s.sign([]byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func (s *JwtSigner) algorithm() string {
+	if _, ok := s.key.Public().(ed25519.PublicKey); ok {
+		return "EdDSA"
+	}
+	return "ES256"
+}
+
+// sign signs input through the crypto.Signer, converting ECDSA's ASN.1
+// signature to the fixed-width form JWTs use.
+func (s *JwtSigner) sign(input []byte) ([]byte, error) {
+	if _, ok := s.key.Public().(ed25519.PublicKey); ok {
+		return s.key.Sign(rand.Reader, input, crypto.Hash(0))
+	}
+
+	digest := sha256.Sum256(input)
+	der, err := s.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed struct{ R, S *big.Int }
+	if _, err := asn1.Unmarshal(der, &parsed); err != nil {
+		return nil, fmt.Errorf("unable to parse ECDSA signature: %w", err)
+	}
+
+	signature := make([]byte, 64)
+	parsed.R.FillBytes(signature[:32])
+	parsed.S.FillBytes(signature[32:])
+	return signature, nil
+}
+
+// unixCeil returns t in unix seconds, rounded up so a token never expires
+// before t.
+func unixCeil(t time.Time) int64 {
+	if t.Equal(time.Unix(t.Unix(), 0)) {
+		return t.Unix()
+	}
+	return t.Unix() + 1
+}
+
+func (s *JwtSigner) ttl() time.Duration {
+	if s.Ttl <= 0 {
+		return 2 * time.Minute
+	}
+	return s.Ttl
+}
+
+func encodeJwtPart(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// JwtTokenCache reuses minted tokens per key and uri until shortly before they
+// expire, instead of signing a fresh JWT for every request.
+type JwtTokenCache struct {
+	// RefreshBefore is how long before expiry a token is replaced. Defaults
+	// to 30 seconds, and is capped at half the signer's Ttl so short-lived
+	// tokens are still reused.
+	RefreshBefore time.Duration
+
+	mu        sync.Mutex
+	entries   map[jwtCacheKey]cachedToken
+	nextSweep time.Time
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+type jwtCacheKey struct {
+	keyName string
+	uri     string
+}
+
+// jwtSweepInterval spaces out JwtTokenCache's sweeps of expired tokens, so a
+// miss does not scan every entry.
+const jwtSweepInterval = time.Minute
+
+type cachedToken struct {
+	token     string
+	expiresAt time.Time
+}
+
+// DefaultJwtTokenCache is used by signers created through NewSigner.
+var DefaultJwtTokenCache = &JwtTokenCache{}
+
+// JwtTokenCacheStats reports cache effectiveness.
+type JwtTokenCacheStats struct {
+	Hits    uint64
+	Misses  uint64
+	Entries int
+}
+
+// Token returns a cached token for signer and uri, minting one when there is
+// none or it is close to expiry.
+func (c *JwtTokenCache) Token(signer *JwtSigner, uri string, t time.Time) (string, error) {
+	key := jwtCacheKey{keyName: signer.KeyName, uri: uri}
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+
+	if ok && t.Before(entry.expiresAt.Add(-c.refreshBefore(signer.ttl()))) {
+		c.hits.Add(1)
+		return entry.token, nil
+	}
+	c.misses.Add(1)
+
+	token, err := signer.Token(uri, t)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = map[jwtCacheKey]cachedToken{}
+	}
+	if !t.Before(c.nextSweep) {
+		for k, e := range c.entries {
+			if !t.Before(e.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		c.nextSweep = t.Add(jwtSweepInterval)
+	}
+	c.entries[key] = cachedToken{token: token, expiresAt: t.Add(signer.ttl())}
+
+	return token, nil
+}
+
+// Invalidate drops cached tokens for keyName, e.g. after the key is rotated or
+// a request is rejected with 401.
+func (c *JwtTokenCache) Invalidate(keyName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k := range c.entries {
+		if k.keyName == keyName {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// Purge drops every cached token.
+func (c *JwtTokenCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = nil
+}
+
+func (c *JwtTokenCache) Stats() JwtTokenCacheStats {
+	c.mu.Lock()
+	entries := len(c.entries)
+	c.mu.Unlock()
+
+	return JwtTokenCacheStats{Hits: c.hits.Load(), Misses: c.misses.Load(), Entries: entries}
+}
+
+// refreshBefore is the refresh window for tokens valid for ttl.
+func (c *JwtTokenCache) refreshBefore(ttl time.Duration) time.Duration {
+	refreshBefore := c.RefreshBefore
+	if refreshBefore <= 0 {
+		refreshBefore = 30 * time.Second
+	}
+	if refreshBefore > ttl/2 {
+		return ttl / 2
+	}
+	return refreshBefore
+}
+
+// JwtHeaderFunc returns a HeaderFunc that sets a bearer token minted by
+// signer. When cache is set, tokens are reused across requests to the same
+// uri. If minting fails the call fails with a *SigningError.
+func JwtHeaderFunc(signer *JwtSigner, cache *JwtTokenCache) HeaderFunc {
+	if cache == nil {
+		return SignerHeaderFunc(signer)
+	}
+	return SignerHeaderFunc(&cachingJwtSigner{signer: signer, cache: cache})
+}
+
+// JwtAuthDecorator returns an AuthDecorator that sets the "jwt" field of
+// Advanced Trade websocket subscribe payloads to a token minted by signer at
+// the time of encoding, read from clock (SystemClock when nil). Tokens are
+// never cached, so a subscription replayed after a reconnect carries a token
+// that is still valid.
+func JwtAuthDecorator(signer *JwtSigner, clock Clock) AuthDecorator {
+	if clock == nil {
+		clock = SystemClock
+	}
+	return AuthDecoratorFunc(func(ctx context.Context, message SubscribeMessage) error {
+		token, err := signer.Token("", clock.Now())
+		if err != nil {
+			return &SigningError{Err: err}
+		}
+		message["jwt"] = token
+		return nil
+	})
+}
+
+// JwtSignatureHeaderFunc parses a CDP key name and PEM private key and
+// returns a HeaderFunc setting a bearer token, with tokens cached in
+// DefaultJwtTokenCache.
+func JwtSignatureHeaderFunc(keyName, pemKey string) (HeaderFunc, error) {
+	signer, err := NewJwtSigner(keyName, pemKey)
+	if err != nil {
+		return nil, err
+	}
+	return JwtHeaderFunc(signer, DefaultJwtTokenCache), nil
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestJwtSigner(t *testing.T, ttl time.Duration) *JwtSigner {
+	t.Helper()
+	signer, err := NewJwtSignerFromKey("organizations/test/apiKeys/test", ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer.Ttl = ttl
+	return signer
+}
+
+func TestJwtTokenCacheReusesTokensShorterThanRefreshBefore(t *testing.T) {
+	cache := &JwtTokenCache{}
+	signer := newTestJwtSigner(t, 20*time.Second)
+	start := time.Now()
+
+	first, err := cache.Token(signer, "GET api.coinbase.com/accounts", start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := cache.Token(signer, "GET api.coinbase.com/accounts", start.Add(5*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Fatal("20s token was not reused 5s later with the default 30s RefreshBefore")
+	}
+
+	third, err := cache.Token(signer, "GET api.coinbase.com/accounts", start.Add(10*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third == first {
+		t.Fatal("token was reused within half its ttl of expiring")
+	}
+	if stats := cache.Stats(); stats.Hits != 1 || stats.Misses != 2 {
+		t.Fatalf("stats = %+v, want 1 hit and 2 misses", stats)
+	}
+}
+
+func TestJwtTokenCacheSweepsExpiredTokensPeriodically(t *testing.T) {
+	cache := &JwtTokenCache{}
+	signer := newTestJwtSigner(t, 10*time.Second)
+	start := time.Now()
+
+	cache.Token(signer, "GET api.coinbase.com/a", start)
+	cache.Token(signer, "GET api.coinbase.com/b", start.Add(30*time.Second))
+	if n := cache.Stats().Entries; n != 2 {
+		t.Fatalf("%d entries after a miss within the sweep interval, want 2", n)
+	}
+
+	cache.Token(signer, "GET api.coinbase.com/c", start.Add(61*time.Second))
+	if n := cache.Stats().Entries; n != 1 {
+		t.Fatalf("%d entries after a due sweep, want only the new token", n)
+	}
+}
+
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time { return time.Time(c) }
+
+func TestJwtAuthDecoratorMintsTokenPerEncode(t *testing.T) {
+	signer := newTestJwtSigner(t, 0)
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	message := SubscribeMessage{"type": "subscribe", "channel": "user"}
+	decorator := JwtAuthDecorator(signer, fixedClock(at))
+
+	var tokens []string
+	for i := 0; i < 2; i++ {
+		data, err := EncodeSubscribeMessage(context.Background(), message, decorator)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded struct {
+			Jwt string `json:"jwt"`
+		}
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		tokens = append(tokens, decoded.Jwt)
+	}
+	if tokens[0] == "" || tokens[0] == tokens[1] {
+		t.Fatalf("tokens = %q, want a fresh token on each encode", tokens)
+	}
+
+	parts := strings.Split(tokens[0], ".")
+	if len(parts) != 3 {
+		t.Fatalf("token %q is not a JWT", tokens[0])
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims["nbf"] != float64(at.Unix()) || claims["sub"] != signer.KeyName {
+		t.Fatalf("claims = %v, want nbf from the clock and sub %q", claims, signer.KeyName)
+	}
+	if _, ok := claims["uri"]; ok {
+		t.Fatal("websocket token carries a uri claim")
+	}
+}
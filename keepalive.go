@@ -0,0 +1,133 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrConnectionDead is returned by Keepalive.Run when nothing has been
+// received on a connection for longer than its Timeout.
+var ErrConnectionDead = errors.New("connection went silent")
+
+// Keepalive pings a long-lived connection, such as a websocket, and watches
+// that something comes back. The reader calls Touch for every message and
+// pong; when nothing arrives within Timeout, Run closes the connection and
+// returns ErrConnectionDead, so a reader blocked on a silently dead socket
+// wakes up and can reconnect.
+type Keepalive struct {
+	// Interval is the time between pings. Defaults to 30 seconds.
+	Interval time.Duration
+
+	// Timeout is how long the connection may stay silent before it is
+	// declared dead. Defaults to twice Interval.
+	Timeout time.Duration
+
+	// Ping sends a ping, e.g. gorilla's WriteControl with a PingMessage. A
+	// failed ping ends Run with its error. Nil sends no pings, which suits
+	// feeds whose server sends its own heartbeats.
+	Ping func(ctx context.Context) error
+
+	// Close, when set, is called once before Run returns because the
+	// connection is dead or a ping failed.
+	Close func() error
+
+	// Clock times the pings and the watchdog. Defaults to SystemClock; a
+	// clock such as testutil.FakeClock makes Run deterministic in tests.
+	Clock Clock
+
+	mu       sync.Mutex
+	lastSeen time.Time
+}
+
+// Touch records that something was received on the connection.
+func (k *Keepalive) Touch() {
+	now := k.clock().Now()
+	k.mu.Lock()
+	k.lastSeen = now
+	k.mu.Unlock()
+}
+
+// Run pings every Interval and watches for silence until ctx is done, a ping
+// fails, or the connection is declared dead. The silence is measured from the
+// later of the start of Run and the last Touch.
+func (k *Keepalive) Run(ctx context.Context) error {
+	interval := k.Interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	timeout := k.Timeout
+	if timeout <= 0 {
+		timeout = 2 * interval
+	}
+
+	k.Touch()
+
+	ticker := newTicker(k.Clock, interval)
+	defer ticker.Stop()
+	deadline := newTimer(k.Clock, timeout)
+	defer func() { deadline.Stop() }()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C():
+			if k.Ping == nil {
+				continue
+			}
+			if err := k.Ping(ctx); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				k.close()
+				return fmt.Errorf("keepalive ping: %w", err)
+			}
+		case <-deadline.C():
+			silence := k.silence()
+			if silence >= timeout {
+				k.close()
+				return fmt.Errorf("%w: nothing received for %s", ErrConnectionDead, silence)
+			}
+			deadline = newTimer(k.Clock, timeout-silence)
+		}
+	}
+}
+
+func (k *Keepalive) silence() time.Duration {
+	now := k.clock().Now()
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return now.Sub(k.lastSeen)
+}
+
+func (k *Keepalive) close() {
+	if k.Close != nil {
+		k.Close()
+	}
+}
+
+func (k *Keepalive) clock() Clock {
+	if k.Clock == nil {
+		return SystemClock
+	}
+	return k.Clock
+}
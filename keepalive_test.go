@@ -0,0 +1,101 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coinbase-samples/core-go"
+	"github.com/coinbase-samples/core-go/testutil"
+)
+
+func TestKeepaliveDeclaresASilentConnectionDead(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Time{})
+	var pings, closes atomic.Int32
+	k := &core.Keepalive{
+		Interval: 10 * time.Second,
+		Timeout:  25 * time.Second,
+		Ping:     func(context.Context) error { pings.Add(1); return nil },
+		Close:    func() error { closes.Add(1); return nil },
+		Clock:    clock,
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- k.Run(context.Background()) }()
+
+	clock.BlockUntil(2)
+	clock.Advance(10 * time.Second)
+	k.Touch()
+	clock.Advance(20 * time.Second)
+
+	// The deadline at 25s found the Touch at 10s and was rearmed for 35s.
+	clock.BlockUntil(2)
+	select {
+	case err := <-done:
+		t.Fatalf("Run returned %v while the connection was alive", err)
+	default:
+	}
+
+	clock.Advance(10 * time.Second)
+	select {
+	case err := <-done:
+		if !errors.Is(err, core.ErrConnectionDead) {
+			t.Fatalf("err = %v, want ErrConnectionDead", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not declare the connection dead")
+	}
+	if closes.Load() != 1 {
+		t.Fatalf("Close called %d times, want 1", closes.Load())
+	}
+	if pings.Load() == 0 {
+		t.Fatal("no pings were sent")
+	}
+}
+
+func TestKeepaliveClosesOnPingFailure(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Time{})
+	failure := errors.New("broken pipe")
+	var closes atomic.Int32
+	k := &core.Keepalive{
+		Interval: time.Second,
+		Ping:     func(context.Context) error { return failure },
+		Close:    func() error { closes.Add(1); return nil },
+		Clock:    clock,
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- k.Run(context.Background()) }()
+
+	clock.BlockUntil(2)
+	clock.Advance(time.Second)
+	select {
+	case err := <-done:
+		if !errors.Is(err, failure) {
+			t.Fatalf("err = %v, want the ping error", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after a failed ping")
+	}
+	if closes.Load() != 1 {
+		t.Fatalf("Close called %d times, want 1", closes.Load())
+	}
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var ErrKillSwitchTripped = errors.New("kill switch tripped")
+
+// KillSwitchError is returned for calls rejected by a tripped KillSwitch. It
+// matches ErrKillSwitchTripped with errors.Is.
+type KillSwitchError struct {
+	Reason     string
+	TrippedAt  time.Time
+	HttpMethod string
+	Path       string
+}
+
+func (e *KillSwitchError) Error() string {
+	return fmt.Sprintf("%v: %s %s rejected: %s", ErrKillSwitchTripped, e.HttpMethod, e.Path, e.Reason)
+}
+
+func (e *KillSwitchError) Is(target error) bool {
+	return target == ErrKillSwitchTripped
+}
+
+// KillSwitch, once tripped, rejects new mutating calls while still allowing
+// reads and calls tagged PriorityCritical, such as order cancels.
+type KillSwitch struct {
+	mu        sync.RWMutex
+	tripped   bool
+	reason    string
+	trippedAt time.Time
+}
+
+// DefaultKillSwitch is the process-wide switch consulted by every call.
+var DefaultKillSwitch = &KillSwitch{}
+
+// Trip engages the switch. Tripping an engaged switch keeps the original reason.
+func (k *KillSwitch) Trip(reason string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.tripped {
+		return
+	}
+	k.tripped = true
+	k.reason = reason
+	k.trippedAt = time.Now()
+}
+
+func (k *KillSwitch) Reset() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.tripped = false
+	k.reason = ""
+	k.trippedAt = time.Time{}
+}
+
+func (k *KillSwitch) Tripped() bool {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.tripped
+}
+
+// Allow returns a *KillSwitchError when the switch is tripped and the call is
+// neither a read nor tagged PriorityCritical.
+func (k *KillSwitch) Allow(ctx context.Context, httpMethod, path string) error {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	if !k.tripped || !isMutatingMethod(httpMethod) || PriorityFromContext(ctx) >= PriorityCritical {
+		return nil
+	}
+
+	return &KillSwitchError{
+		Reason:     k.reason,
+		TrippedAt:  k.trippedAt,
+		HttpMethod: httpMethod,
+		Path:       path,
+	}
+}
+
+func isMutatingMethod(httpMethod string) bool {
+	switch httpMethod {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	default:
+		return true
+	}
+}
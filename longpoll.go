@@ -0,0 +1,150 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// LongPollBatch is one non-empty poll result. Cursor resumes after it.
+type LongPollBatch[T any] struct {
+	Items  []T
+	Cursor string
+}
+
+// LongPoller repeatedly GETs a "changes since cursor" endpoint, passing the
+// cursor from the previous response, and delivers each batch on a channel.
+// A 204 or a poll that reaches PollTimeout means nothing changed and the next
+// poll starts at once; other failures back off before retrying.
+type LongPoller[T any] struct {
+	Client      Client
+	Path        string
+	HeadersFunc HeaderFunc
+
+	// CursorParam is the query parameter carrying the cursor.
+	CursorParam string
+
+	// Cursor is where polling starts. Empty starts from the server's
+	// default.
+	Cursor string
+
+	// Decode extracts the items and next cursor from a response body.
+	Decode func(body []byte) (items []T, cursor string, err error)
+
+	// PollTimeout bounds each poll. Zero leaves polls bounded only by the
+	// client's own timeout.
+	PollTimeout time.Duration
+
+	// Backoff spaces retries after failures. Defaults to 1 second doubling
+	// to 30 seconds.
+	Backoff *RetryPolicy
+
+	// OnError, when set, receives failures before the backoff.
+	OnError func(err error)
+}
+
+// Run polls until ctx is done, then closes the returned channel.
+func (p *LongPoller[T]) Run(ctx context.Context) <-chan LongPollBatch[T] {
+	batches := make(chan LongPollBatch[T])
+
+	go func() {
+		defer close(batches)
+
+		cursor := p.Cursor
+		failures := 0
+		for ctx.Err() == nil {
+			items, next, err := p.poll(ctx, cursor)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				failures++
+				if p.OnError != nil {
+					p.OnError(err)
+				}
+				if !sleepContext(ctx, p.backoff().Delay(failures, 0)) {
+					return
+				}
+				continue
+			}
+			failures = 0
+
+			if next != "" {
+				cursor = next
+			}
+			if len(items) == 0 {
+				continue
+			}
+
+			select {
+			case batches <- LongPollBatch[T]{Items: items, Cursor: cursor}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return batches
+}
+
+func (p *LongPoller[T]) poll(ctx context.Context, cursor string) ([]T, string, error) {
+	pollCtx := ctx
+	if p.PollTimeout > 0 {
+		var cancel context.CancelFunc
+		pollCtx, cancel = context.WithTimeout(ctx, p.PollTimeout)
+		defer cancel()
+	}
+
+	query := NewQueryBuilder(0).AddIfNotEmpty(p.CursorParam, cursor).String()
+
+	var body []byte
+	err := call(pollCtx, p.Client, p.Path, query, http.MethodGet, []int{http.StatusOK, http.StatusNoContent}, nil, &body, p.HeadersFunc)
+	if err != nil {
+		if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+			return nil, cursor, nil
+		}
+		return nil, "", err
+	}
+	if len(body) == 0 {
+		return nil, cursor, nil
+	}
+
+	return p.Decode(body)
+}
+
+func (p *LongPoller[T]) backoff() *RetryPolicy {
+	if p.Backoff != nil {
+		return p.Backoff
+	}
+	return &RetryPolicy{BaseDelay: time.Second, MaxDelay: 30 * time.Second}
+}
+
+// sleepContext waits for d and reports false if ctx ended first.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
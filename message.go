@@ -0,0 +1,82 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// PooledMessage is a message read into a buffer borrowed from a BufferPool.
+// Its bytes are valid until Release, which must be called exactly once.
+type PooledMessage struct {
+	buf  *bytes.Buffer
+	pool *BufferPool
+}
+
+var pooledMessages = sync.Pool{New: func() interface{} { return &PooledMessage{} }}
+
+// Bytes returns the message. The slice must not be used after Release.
+func (m *PooledMessage) Bytes() []byte {
+	return m.buf.Bytes()
+}
+
+// Release returns the buffer to its pool.
+func (m *PooledMessage) Release() {
+	m.pool.Put(m.buf)
+	m.buf, m.pool = nil, nil
+	pooledMessages.Put(m)
+}
+
+// ReadPooledMessage reads r to the end into a buffer from pool, or
+// DefaultBufferPool when nil. r is typically the reader for one frame
+// returned by a websocket library, such as gorilla's Conn.NextReader, so a
+// high-volume consumer does not allocate a new slice per message.
+func ReadPooledMessage(r io.Reader, pool *BufferPool) (*PooledMessage, error) {
+	if pool == nil {
+		pool = DefaultBufferPool
+	}
+
+	buf := pool.Get(0)
+	if _, err := buf.ReadFrom(r); err != nil {
+		pool.Put(buf)
+		return nil, err
+	}
+
+	m := pooledMessages.Get().(*PooledMessage)
+	m.buf, m.pool = buf, pool
+	return m, nil
+}
+
+// ReadPooledMessages calls next for each message's reader and passes the
+// message to handle, until next or a read fails. handle borrows the message
+// and must Release it, either before returning or later, e.g. after another
+// goroutine has processed it.
+func ReadPooledMessages(next func() (io.Reader, error), pool *BufferPool, handle func(message *PooledMessage)) error {
+	for {
+		r, err := next()
+		if err != nil {
+			return err
+		}
+		message, err := ReadPooledMessage(r, pool)
+		if err != nil {
+			return err
+		}
+		handle(message)
+	}
+}
@@ -0,0 +1,56 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReadPooledMessagesReusesBuffers(t *testing.T) {
+	pool := NewBufferPool(1 << 10)
+	frames := []string{`{"type":"ticker","price":"1"}`, `{"type":"ticker","price":"2"}`, `{"type":"ticker","price":"3"}`}
+	closed := errors.New("connection closed")
+
+	i := 0
+	next := func() (io.Reader, error) {
+		if i == len(frames) {
+			return nil, closed
+		}
+		i++
+		return strings.NewReader(frames[i-1]), nil
+	}
+
+	var received []string
+	err := ReadPooledMessages(next, pool, func(message *PooledMessage) {
+		received = append(received, string(message.Bytes()))
+		message.Release()
+	})
+	if !errors.Is(err, closed) {
+		t.Fatalf("err = %v, want the reader's error", err)
+	}
+	if strings.Join(received, "\n") != strings.Join(frames, "\n") {
+		t.Fatalf("received %q, want %q", received, frames)
+	}
+
+	stats := pool.Stats()
+	if stats.Gets != 3 || stats.Puts != 3 {
+		t.Fatalf("stats = %+v, want a buffer borrowed and returned per message", stats)
+	}
+}
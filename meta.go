@@ -0,0 +1,99 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"sort"
+)
+
+// MetaKey identifies a typed metadata value carried on a call's context, such
+// as a strategy or tenant ID. Keys compare by identity, so declare each one
+// once as a package-level variable.
+type MetaKey[T any] struct {
+	name string
+}
+
+// NewMetaKey returns a key whose values are logged under name.
+func NewMetaKey[T any](name string) *MetaKey[T] {
+	return &MetaKey[T]{name: name}
+}
+
+// Name is the name the key was created with.
+func (k *MetaKey[T]) Name() string {
+	return k.name
+}
+
+type metaContextKey struct{}
+
+type metaEntry struct {
+	parent *metaEntry
+	key    any
+	name   string
+	value  any
+}
+
+// WithMeta returns a context carrying value under key. Middleware, hooks, and
+// HeaderFuncs (through the request's context) can read it back with Meta.
+func WithMeta[T any](ctx context.Context, key *MetaKey[T], value T) context.Context {
+	parent, _ := ctx.Value(metaContextKey{}).(*metaEntry)
+	return context.WithValue(ctx, metaContextKey{}, &metaEntry{parent: parent, key: key, name: key.name, value: value})
+}
+
+// Meta returns the value set for key by WithMeta.
+func Meta[T any](ctx context.Context, key *MetaKey[T]) (T, bool) {
+	entry, _ := ctx.Value(metaContextKey{}).(*metaEntry)
+	for ; entry != nil; entry = entry.parent {
+		if entry.key == any(key) {
+			return entry.value.(T), true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// MetaValues returns every metadata value on ctx by key name, for logging and
+// audit. When a key was set more than once the latest value wins.
+func MetaValues(ctx context.Context) map[string]any {
+	entry, _ := ctx.Value(metaContextKey{}).(*metaEntry)
+	if entry == nil {
+		return nil
+	}
+
+	values := map[string]any{}
+	seen := map[any]bool{}
+	for ; entry != nil; entry = entry.parent {
+		if seen[entry.key] {
+			continue
+		}
+		seen[entry.key] = true
+		if _, ok := values[entry.name]; !ok {
+			values[entry.name] = entry.value
+		}
+	}
+	return values
+}
+
+// metaNames returns the names in values in sorted order.
+func metaNames(values map[string]any) []string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,242 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MetricsRecorder receives one observation per completed call. statusCode is
+// zero when no response was received.
+type MetricsRecorder interface {
+	RecordRequest(httpMethod, path string, statusCode int, duration time.Duration)
+}
+
+// RetryRecorder is optionally implemented by a MetricsRecorder to count
+// retries. statusCode is that of the failed attempt, zero when no response
+// was received, and attempt counts the retries from 1.
+type RetryRecorder interface {
+	RecordRetry(httpMethod, path string, statusCode, attempt int)
+}
+
+func recordRequest(ctx context.Context, config *Config, request *ApiRequest, statusCode int, start time.Time) {
+	if config.Metrics == nil {
+		return
+	}
+	config.Metrics.RecordRequest(request.HttpMethod, metricsPath(ctx, config, request.Path), statusCode, time.Since(start))
+}
+
+func recordRetry(ctx context.Context, config *Config, request *ApiRequest, statusCode, attempt int) {
+	recorder, ok := config.Metrics.(RetryRecorder)
+	if !ok {
+		return
+	}
+	recorder.RecordRetry(request.HttpMethod, metricsPath(ctx, config, request.Path), statusCode, attempt)
+}
+
+type multiRecorder []MetricsRecorder
+
+// MultiMetricsRecorder fans every observation out to each of recorders.
+func MultiMetricsRecorder(recorders ...MetricsRecorder) MetricsRecorder {
+	return multiRecorder(recorders)
+}
+
+func (m multiRecorder) RecordRequest(httpMethod, path string, statusCode int, duration time.Duration) {
+	for _, r := range m {
+		r.RecordRequest(httpMethod, path, statusCode, duration)
+	}
+}
+
+func (m multiRecorder) RecordRetry(httpMethod, path string, statusCode, attempt int) {
+	for _, r := range m {
+		if recorder, ok := r.(RetryRecorder); ok {
+			recorder.RecordRetry(httpMethod, path, statusCode, attempt)
+		}
+	}
+}
+
+type pathTemplateKey struct{}
+
+// WithPathTemplate sets the path label recorded for calls made with the
+// returned context, e.g. "/orders/{order_id}", in place of the raw path.
+func WithPathTemplate(ctx context.Context, template string) context.Context {
+	return context.WithValue(ctx, pathTemplateKey{}, template)
+}
+
+func metricsPath(ctx context.Context, config *Config, path string) string {
+	if template, ok := ctx.Value(pathTemplateKey{}).(string); ok && template != "" {
+		return template
+	}
+	if config.MetricsRawPaths {
+		return path
+	}
+	return TemplatePath(path)
+}
+
+var idSegment = regexp.MustCompile(`^(?:[0-9]+|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|[0-9a-fA-F]{16,}|.*[0-9].*[0-9].*[0-9].*)$`)
+
+// TemplatePath replaces path segments that look like identifiers (numbers,
+// UUIDs, long hex strings, or anything containing three or more digits) with
+// "{id}", so metric labels do not grow with every order or account.
+// Segments such as "v3" or "BTC-USD" are kept.
+func TemplatePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if segment != "" && idSegment.MatchString(segment) {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// DefaultLatencyBuckets are the upper bounds used when a LatencyHistogram is
+// created without buckets.
+var DefaultLatencyBuckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// OverflowSeries is the path label that series beyond a histogram's series
+// limit are folded into.
+const OverflowSeries = "{other}"
+
+// HistogramSeries identifies one labeled latency series.
+type HistogramSeries struct {
+	HttpMethod string
+	Path       string
+	StatusCode int
+}
+
+// HistogramSnapshot is the state of one series. Counts[i] is the number of
+// observations at or below Buckets[i]; the final count is the +Inf bucket.
+type HistogramSnapshot struct {
+	Series  HistogramSeries
+	Buckets []time.Duration
+	Counts  []uint64
+	Sum     time.Duration
+	Count   uint64
+}
+
+type histogramData struct {
+	counts []uint64
+	sum    time.Duration
+	count  uint64
+}
+
+// LatencyHistogram is an in-memory MetricsRecorder with configurable buckets.
+// Once its series limit is reached, further series are recorded under
+// OverflowSeries so a path that slipped past templating cannot grow memory
+// without bound.
+type LatencyHistogram struct {
+	buckets   []time.Duration
+	maxSeries int
+
+	mu     sync.Mutex
+	series map[HistogramSeries]*histogramData
+}
+
+// NewLatencyHistogram returns a histogram with the given bucket upper bounds,
+// or DefaultLatencyBuckets when empty, tracking at most maxSeries series (no
+// limit when maxSeries < 1).
+func NewLatencyHistogram(buckets []time.Duration, maxSeries int) *LatencyHistogram {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	sorted := append([]time.Duration(nil), buckets...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return &LatencyHistogram{
+		buckets:   sorted,
+		maxSeries: maxSeries,
+		series:    make(map[HistogramSeries]*histogramData),
+	}
+}
+
+func (h *LatencyHistogram) RecordRequest(httpMethod, path string, statusCode int, duration time.Duration) {
+	key := HistogramSeries{HttpMethod: httpMethod, Path: path, StatusCode: statusCode}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	data, ok := h.series[key]
+	if !ok {
+		if h.maxSeries > 0 && len(h.series) >= h.maxSeries {
+			key = HistogramSeries{HttpMethod: httpMethod, Path: OverflowSeries, StatusCode: statusCode}
+			data = h.series[key]
+		}
+		if data == nil {
+			data = &histogramData{counts: make([]uint64, len(h.buckets)+1)}
+			h.series[key] = data
+		}
+	}
+
+	i := sort.Search(len(h.buckets), func(i int) bool { return duration <= h.buckets[i] })
+	data.counts[i]++
+	data.sum += duration
+	data.count++
+}
+
+// Snapshot returns the current state of every series, with cumulative bucket
+// counts, ordered by method, path, and status.
+func (h *LatencyHistogram) Snapshot() []HistogramSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	snapshots := make([]HistogramSnapshot, 0, len(h.series))
+	for key, data := range h.series {
+		counts := make([]uint64, len(data.counts))
+		var cumulative uint64
+		for i, c := range data.counts {
+			cumulative += c
+			counts[i] = cumulative
+		}
+		snapshots = append(snapshots, HistogramSnapshot{
+			Series:  key,
+			Buckets: h.buckets,
+			Counts:  counts,
+			Sum:     data.sum,
+			Count:   data.count,
+		})
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		a, b := snapshots[i].Series, snapshots[j].Series
+		if a.HttpMethod != b.HttpMethod {
+			return a.HttpMethod < b.HttpMethod
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.StatusCode < b.StatusCode
+	})
+
+	return snapshots
+}
@@ -0,0 +1,33 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import "context"
+
+// CallFunc executes a single call and returns its response.
+type CallFunc func(ctx context.Context, request *ApiRequest, headersFunc HeaderFunc) *ApiResponse
+
+// Middleware wraps a CallFunc to add behavior around every call.
+type Middleware func(next CallFunc) CallFunc
+
+// chainMiddleware wraps final so that the first middleware is outermost.
+func chainMiddleware(middleware []Middleware, final CallFunc) CallFunc {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		final = middleware[i](final)
+	}
+	return final
+}
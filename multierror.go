@@ -0,0 +1,122 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ItemError ties an error to the position of the item that produced it in a
+// batch.
+type ItemError struct {
+	Index int
+	Err   error
+}
+
+func (e *ItemError) Error() string {
+	return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+}
+
+func (e *ItemError) Unwrap() error {
+	return e.Err
+}
+
+// MultiError aggregates the errors of a batch operation. errors.Is and
+// errors.As inspect every contained error.
+type MultiError struct {
+	Errors []error
+}
+
+func (m *MultiError) Error() string {
+	switch len(m.Errors) {
+	case 0:
+		return "no errors"
+	case 1:
+		return m.Errors[0].Error()
+	}
+
+	messages := make([]string, len(m.Errors))
+	for i, err := range m.Errors {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("%d errors occurred: %s", len(m.Errors), strings.Join(messages, "; "))
+}
+
+func (m *MultiError) Unwrap() []error {
+	return m.Errors
+}
+
+// Append adds err, ignoring nil.
+func (m *MultiError) Append(err error) {
+	if err != nil {
+		m.Errors = append(m.Errors, err)
+	}
+}
+
+// ErrorOrNil returns m when it holds at least one error and nil otherwise, so
+// an empty MultiError is never returned as a non-nil error.
+func (m *MultiError) ErrorOrNil() error {
+	if m == nil || len(m.Errors) == 0 {
+		return nil
+	}
+	return m
+}
+
+// ParallelCalls runs calls with at most limit running at once (unbounded when
+// limit < 1) and waits for all of them. Failures are returned as a
+// *MultiError of *ItemError ordered by call index.
+func ParallelCalls(ctx context.Context, limit int, calls ...func(ctx context.Context) error) error {
+	if limit < 1 || limit > len(calls) {
+		limit = len(calls)
+	}
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		errs   []*ItemError
+		tokens = make(chan struct{}, limit)
+	)
+
+	for i, c := range calls {
+		tokens <- struct{}{}
+		wg.Add(1)
+		go func(i int, c func(ctx context.Context) error) {
+			defer func() {
+				<-tokens
+				wg.Done()
+			}()
+			if err := c(ctx); err != nil {
+				mu.Lock()
+				errs = append(errs, &ItemError{Index: i, Err: err})
+				mu.Unlock()
+			}
+		}(i, c)
+	}
+	wg.Wait()
+
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Index < errs[j].Index })
+
+	multi := &MultiError{}
+	for _, err := range errs {
+		multi.Append(err)
+	}
+	return multi.ErrorOrNil()
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Nullable is a JSON field that tells apart absent, explicit null, and a
+// value, including the zero value, as PATCH style updates need: absent
+// leaves a field unchanged and null clears it.
+//
+// The zero Nullable is absent. Declare fields with omitempty so absent ones
+// are left out of requests:
+//
+//	type UpdateOrder struct {
+//		Limit core.Nullable[string] `json:"limit_price,omitempty"`
+//	}
+//
+// It is a map so that omitempty applies; use the functions and methods
+// rather than indexing it.
+type Nullable[T any] map[bool]T
+
+// NewNullable returns a Nullable holding v.
+func NewNullable[T any](v T) Nullable[T] {
+	return Nullable[T]{true: v}
+}
+
+// NewNull returns an explicit null.
+func NewNull[T any]() Nullable[T] {
+	var zero T
+	return Nullable[T]{false: zero}
+}
+
+// IsSpecified reports whether the field is present, as null or a value.
+func (n Nullable[T]) IsSpecified() bool {
+	return len(n) != 0
+}
+
+// IsNull reports whether the field is an explicit null.
+func (n Nullable[T]) IsNull() bool {
+	_, ok := n[false]
+	return ok
+}
+
+// Get returns the value and whether there is one. It reports false for both
+// absent and null.
+func (n Nullable[T]) Get() (T, bool) {
+	v, ok := n[true]
+	return v, ok
+}
+
+// Set replaces the contents with v.
+func (n *Nullable[T]) Set(v T) {
+	*n = NewNullable(v)
+}
+
+// SetNull replaces the contents with an explicit null.
+func (n *Nullable[T]) SetNull() {
+	*n = NewNull[T]()
+}
+
+// Unset makes the field absent.
+func (n *Nullable[T]) Unset() {
+	*n = nil
+}
+
+// MarshalJSON encodes the value, or null when null or absent. Absent fields
+// are only left out by omitempty.
+func (n Nullable[T]) MarshalJSON() ([]byte, error) {
+	if v, ok := n.Get(); ok {
+		return json.Marshal(v)
+	}
+	return []byte("null"), nil
+}
+
+// UnmarshalJSON decodes null or a value. A field missing from the input is
+// never passed here and stays absent.
+func (n *Nullable[T]) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		n.SetNull()
+		return nil
+	}
+
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	n.Set(v)
+	return nil
+}
@@ -0,0 +1,478 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OutboxEntry is a mutating request persisted before it is sent.
+type OutboxEntry struct {
+	// Id identifies the entry. Callers can pass their own, such as a client
+	// order id, so a redelivery is recognized as a duplicate by the API.
+	Id string `json:"id"`
+
+	HttpMethod              string `json:"http_method"`
+	Path                    string `json:"path"`
+	Query                   string `json:"query"`
+	Body                    []byte `json:"body"`
+	ExpectedHttpStatusCodes []int  `json:"expected_http_status_codes"`
+
+	CreatedAt time.Time `json:"created_at"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error,omitempty"`
+
+	// NextAttemptAt is when a failed entry is retried.
+	NextAttemptAt time.Time `json:"next_attempt_at,omitempty"`
+
+	// NeedsReconciliation is set when a delivery failed in a way that leaves
+	// unknown whether the API processed it, and a redelivery could be
+	// processed twice. Run leaves such entries alone until Resolve is called.
+	NeedsReconciliation bool `json:"needs_reconciliation,omitempty"`
+}
+
+// OutboxStore persists outbox entries. Put inserts or replaces an entry by
+// Id, and List returns entries oldest first.
+type OutboxStore interface {
+	Put(entry *OutboxEntry) error
+	Delete(id string) error
+	List() ([]*OutboxEntry, error)
+}
+
+// Outbox sends mutating requests only after persisting them, and keeps
+// retrying until each is confirmed, so a request survives a crash between
+// deciding to send it and seeing it accepted. Delivery is at least once:
+// a crash after the API accepts a request but before the entry is deleted
+// resends it, so entries should carry an id the API deduplicates on.
+// Entries are sent one at a time, oldest first; a failing entry waits out
+// its backoff without holding back the ones behind it. Time is read from
+// the client's Config.Clock.
+type Outbox struct {
+	Client      Client
+	Store       OutboxStore
+	HeadersFunc HeaderFunc
+
+	// IdempotencyHeader, when set, carries the entry's Id on every delivery
+	// so the API can drop a redelivery. Without it, an entry with a
+	// non-idempotent method that fails with a transport error is marked
+	// NeedsReconciliation instead of being retried, since the first attempt
+	// may have been processed.
+	IdempotencyHeader string
+
+	// Backoff spaces out redeliveries of a failing entry. Defaults to a
+	// RetryPolicy with its default delays.
+	Backoff *RetryPolicy
+
+	// MaxAttempts gives up on an entry after this many failed deliveries.
+	// Zero retries forever.
+	MaxAttempts int
+
+	// OnDelivered, when set, receives each confirmed entry and the response
+	// body.
+	OnDelivered func(entry *OutboxEntry, response []byte)
+
+	// OnFailed, when set, receives entries that are given up on, either
+	// because the API rejected them or MaxAttempts was reached. They are
+	// removed from the store.
+	OnFailed func(entry *OutboxEntry, err error)
+
+	// OnReconcile, when set, receives entries as they are marked
+	// NeedsReconciliation. The caller should check with the API whether the
+	// request was processed, e.g. by looking up the order by its client id,
+	// and then call Resolve. The entry stays in the store until then.
+	OnReconcile func(entry *OutboxEntry, err error)
+
+	wakeOnce sync.Once
+	wake     chan struct{}
+}
+
+// Enqueue persists a request for delivery by Run. An empty id is replaced by
+// a random one. Expected status codes default to 200.
+func (o *Outbox) Enqueue(id, httpMethod, path, query string, expectedHttpStatusCodes []int, request interface{}) (*OutboxEntry, error) {
+	body, err := marshalRequest(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if id == "" {
+		if id, err = newOutboxId(); err != nil {
+			return nil, err
+		}
+	}
+	if len(expectedHttpStatusCodes) == 0 {
+		expectedHttpStatusCodes = []int{http.StatusOK}
+	}
+
+	entry := &OutboxEntry{
+		Id:                      id,
+		HttpMethod:              httpMethod,
+		Path:                    path,
+		Query:                   query,
+		Body:                    body,
+		ExpectedHttpStatusCodes: expectedHttpStatusCodes,
+		CreatedAt:               configOf(o.Client).now(),
+	}
+	if err := o.Store.Put(entry); err != nil {
+		return nil, fmt.Errorf("persisting outbox entry %s: %w", id, err)
+	}
+
+	select {
+	case o.wakeup() <- struct{}{}:
+	default:
+	}
+	return entry, nil
+}
+
+// Run delivers pending entries, including those left by a previous process,
+// until ctx is done. It returns the context's error, or a store error.
+func (o *Outbox) Run(ctx context.Context) error {
+	for {
+		entries, err := o.Store.List()
+		if err != nil {
+			return fmt.Errorf("listing outbox entries: %w", err)
+		}
+
+		var next time.Time
+		for _, entry := range entries {
+			if entry.NeedsReconciliation {
+				continue
+			}
+			if configOf(o.Client).now().Before(entry.NextAttemptAt) {
+				if next.IsZero() || entry.NextAttemptAt.Before(next) {
+					next = entry.NextAttemptAt
+				}
+				continue
+			}
+
+			retryAt, err := o.deliver(ctx, entry)
+			if err != nil {
+				return err
+			}
+			if !retryAt.IsZero() && (next.IsZero() || retryAt.Before(next)) {
+				next = retryAt
+			}
+		}
+
+		if err := o.wait(ctx, next); err != nil {
+			return err
+		}
+	}
+}
+
+// wait blocks until next, when set, an Enqueue, or the end of ctx.
+func (o *Outbox) wait(ctx context.Context, next time.Time) error {
+	var retry <-chan time.Time
+	if !next.IsZero() {
+		clock := configOf(o.Client).clock()
+		wait := next.Sub(clock.Now())
+		if wait <= 0 {
+			return ctx.Err()
+		}
+		timer := newTimer(clock, wait)
+		defer timer.Stop()
+		retry = timer.C()
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-o.wakeup():
+	case <-retry:
+	}
+	return nil
+}
+
+// deliver sends entry once. A non-zero time means it failed transiently and
+// should be retried then.
+func (o *Outbox) deliver(ctx context.Context, entry *OutboxEntry) (time.Time, error) {
+	headersFunc := o.HeadersFunc
+	if o.IdempotencyHeader != "" {
+		headersFunc = withIdempotencyHeader(o.IdempotencyHeader, entry.Id, headersFunc)
+	}
+
+	var response []byte
+	err := call(WithIdempotencyKey(ctx, entry.Id), o.Client, entry.Path, entry.Query, entry.HttpMethod, entry.ExpectedHttpStatusCodes, entry.Body, &response, headersFunc)
+	if err == nil {
+		if err := o.Store.Delete(entry.Id); err != nil {
+			return time.Time{}, fmt.Errorf("deleting outbox entry %s: %w", entry.Id, err)
+		}
+		if o.OnDelivered != nil {
+			o.OnDelivered(entry, response)
+		}
+		return time.Time{}, nil
+	}
+
+	if ctx.Err() != nil {
+		return time.Time{}, ctx.Err()
+	}
+
+	entry.Attempts++
+	entry.LastError = err.Error()
+
+	var apiErr *ApiError
+	errors.As(err, &apiErr)
+	if o.isAmbiguous(entry, apiErr) {
+		entry.NeedsReconciliation = true
+		if err := o.Store.Put(entry); err != nil {
+			return time.Time{}, fmt.Errorf("persisting outbox entry %s: %w", entry.Id, err)
+		}
+		if o.OnReconcile != nil {
+			o.OnReconcile(entry, err)
+		}
+		return time.Time{}, nil
+	}
+	if !o.isRetryable(entry, apiErr) || (o.MaxAttempts > 0 && entry.Attempts >= o.MaxAttempts) {
+		if err := o.Store.Delete(entry.Id); err != nil {
+			return time.Time{}, fmt.Errorf("deleting outbox entry %s: %w", entry.Id, err)
+		}
+		if o.OnFailed != nil {
+			o.OnFailed(entry, err)
+		}
+		return time.Time{}, nil
+	}
+
+	backoff := o.Backoff
+	if backoff == nil {
+		backoff = &RetryPolicy{}
+	}
+	var retryAfter time.Duration
+	if apiErr != nil {
+		retryAfter = apiErr.RetryAfter
+	}
+	entry.NextAttemptAt = configOf(o.Client).now().Add(backoff.Delay(entry.Attempts, retryAfter))
+
+	if err := o.Store.Put(entry); err != nil {
+		return time.Time{}, fmt.Errorf("persisting outbox entry %s: %w", entry.Id, err)
+	}
+	return entry.NextAttemptAt, nil
+}
+
+// Resolve settles an entry marked NeedsReconciliation. With resend, the
+// entry is delivered again by Run; otherwise, because the API did process
+// it, it is removed from the store.
+func (o *Outbox) Resolve(id string, resend bool) error {
+	if !resend {
+		if err := o.Store.Delete(id); err != nil {
+			return fmt.Errorf("deleting outbox entry %s: %w", id, err)
+		}
+		return nil
+	}
+
+	entries, err := o.Store.List()
+	if err != nil {
+		return fmt.Errorf("listing outbox entries: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.Id != id {
+			continue
+		}
+		entry.NeedsReconciliation = false
+		entry.NextAttemptAt = time.Time{}
+		if err := o.Store.Put(entry); err != nil {
+			return fmt.Errorf("persisting outbox entry %s: %w", id, err)
+		}
+		select {
+		case o.wakeup() <- struct{}{}:
+		default:
+		}
+		return nil
+	}
+	return fmt.Errorf("outbox entry %s not found", id)
+}
+
+func (o *Outbox) wakeup() chan struct{} {
+	o.wakeOnce.Do(func() {
+		o.wake = make(chan struct{}, 1)
+	})
+	return o.wake
+}
+
+// isRetryable reports whether a failed delivery may succeed later: 429, 5xx,
+// and transport errors, the last only when a redelivery cannot be processed
+// twice.
+func (o *Outbox) isRetryable(entry *OutboxEntry, apiErr *ApiError) bool {
+	if apiErr == nil {
+		return false
+	}
+	code := apiErr.CodeReceived
+	if code == 0 {
+		return CancelReasonOf(apiErr) == CancelReasonNone && o.canRedeliver(entry)
+	}
+	return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
+
+// isAmbiguous reports whether a failed delivery may have been processed and
+// cannot safely be sent again. Errors raised before the request left the
+// process are not ambiguous.
+func (o *Outbox) isAmbiguous(entry *OutboxEntry, apiErr *ApiError) bool {
+	if apiErr == nil || apiErr.CodeReceived != 0 || o.canRedeliver(entry) {
+		return false
+	}
+	return CancelReasonOf(apiErr) == CancelReasonNone && !isLocalError(apiErr) &&
+		!errors.Is(apiErr, ErrRateLimited) && !errors.Is(apiErr, ErrHostQueueTimeout)
+}
+
+func (o *Outbox) canRedeliver(entry *OutboxEntry) bool {
+	return o.IdempotencyHeader != "" || isIdempotentMethod(entry.HttpMethod)
+}
+
+func newOutboxId() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// MemoryOutboxStore keeps entries in memory. It does not survive a restart
+// and suits tests and processes that only need retries.
+type MemoryOutboxStore struct {
+	mu      sync.Mutex
+	entries map[string]*OutboxEntry
+}
+
+func NewMemoryOutboxStore() *MemoryOutboxStore {
+	return &MemoryOutboxStore{entries: map[string]*OutboxEntry{}}
+}
+
+func (s *MemoryOutboxStore) Put(entry *OutboxEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copied := *entry
+	s.entries[entry.Id] = &copied
+	return nil
+}
+
+func (s *MemoryOutboxStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, id)
+	return nil
+}
+
+func (s *MemoryOutboxStore) List() ([]*OutboxEntry, error) {
+	s.mu.Lock()
+	entries := make([]*OutboxEntry, 0, len(s.entries))
+	for _, entry := range s.entries {
+		copied := *entry
+		entries = append(entries, &copied)
+	}
+	s.mu.Unlock()
+
+	sortOutboxEntries(entries)
+	return entries, nil
+}
+
+// FileOutboxStore keeps each entry as a JSON file in a directory, written
+// atomically, so entries survive a crash or restart.
+type FileOutboxStore struct {
+	dir string
+}
+
+// NewFileOutboxStore returns a store in dir, creating it when needed.
+func NewFileOutboxStore(dir string) (*FileOutboxStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileOutboxStore{dir: dir}, nil
+}
+
+func (s *FileOutboxStore) Put(entry *OutboxEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".outbox-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(entry.Id))
+}
+
+func (s *FileOutboxStore) Delete(id string) error {
+	if err := os.Remove(s.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *FileOutboxStore) List() ([]*OutboxEntry, error) {
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*OutboxEntry
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(s.dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		entry := &OutboxEntry{}
+		if err := json.Unmarshal(data, entry); err != nil {
+			return nil, fmt.Errorf("parsing outbox entry %s: %w", f.Name(), err)
+		}
+		entries = append(entries, entry)
+	}
+
+	sortOutboxEntries(entries)
+	return entries, nil
+}
+
+func (s *FileOutboxStore) path(id string) string {
+	return filepath.Join(s.dir, url.PathEscape(id)+".json")
+}
+
+func sortOutboxEntries(entries []*OutboxEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].CreatedAt.Equal(entries[j].CreatedAt) {
+			return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+		}
+		return entries[i].Id < entries[j].Id
+	})
+}
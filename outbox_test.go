@@ -0,0 +1,240 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coinbase-samples/core-go"
+	"github.com/coinbase-samples/core-go/testutil"
+)
+
+// runOutbox runs o until the test ends.
+func runOutbox(t *testing.T, o *core.Outbox) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		o.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !cond(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+func pendingEntries(t *testing.T, store core.OutboxStore) []*core.OutboxEntry {
+	t.Helper()
+	entries, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func TestOutboxDeliversEntriesLeftByPreviousProcess(t *testing.T) {
+	var received atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	dir := t.TempDir()
+
+	// The first process persists the entry and stops before sending it.
+	store, err := core.NewFileOutboxStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := &core.Outbox{Client: core.NewClient(server.URL, nil), Store: store, HeadersFunc: noHeaders}
+	if _, err := first.Enqueue("order-1", http.MethodPost, "/orders", "", nil, map[string]string{"side": "BUY"}); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err = core.NewFileOutboxStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	delivered := make(chan string, 1)
+	second := &core.Outbox{
+		Client:      core.NewClient(server.URL, nil),
+		Store:       store,
+		HeadersFunc: noHeaders,
+		OnDelivered: func(entry *core.OutboxEntry, response []byte) { delivered <- entry.Id },
+	}
+	runOutbox(t, second)
+
+	select {
+	case id := <-delivered:
+		if id != "order-1" {
+			t.Fatalf("delivered %q, want order-1", id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("restarted outbox did not deliver the persisted entry")
+	}
+	if n := received.Load(); n != 1 {
+		t.Fatalf("server received %d requests, want 1", n)
+	}
+	if entries := pendingEntries(t, store); len(entries) != 0 {
+		t.Fatalf("%d entries left after delivery, want 0", len(entries))
+	}
+}
+
+func TestOutboxReschedulesFailedEntryWithBackoff(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Time{})
+	client := core.NewClient(failingServer(t, 1).URL, nil)
+	client.Config().Clock = clock
+	store := core.NewMemoryOutboxStore()
+	delivered := make(chan *core.OutboxEntry, 1)
+	o := &core.Outbox{
+		Client:      client,
+		Store:       store,
+		HeadersFunc: noHeaders,
+		Backoff:     &core.RetryPolicy{BaseDelay: time.Minute, MaxDelay: time.Hour},
+		OnDelivered: func(entry *core.OutboxEntry, response []byte) { delivered <- entry },
+	}
+	if _, err := o.Enqueue("order-1", http.MethodPost, "/orders", "", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	start := clock.Now()
+	runOutbox(t, o)
+
+	clock.BlockUntil(1)
+	entries := pendingEntries(t, store)
+	if len(entries) != 1 {
+		t.Fatalf("%d entries after the 503, want 1", len(entries))
+	}
+	if entry := entries[0]; entry.Attempts != 1 || !entry.NextAttemptAt.Equal(start.Add(time.Minute)) {
+		t.Fatalf("entry after the 503 has %d attempts, next at %v; want 1, due a minute after %v", entry.Attempts, entry.NextAttemptAt, start)
+	}
+
+	clock.Advance(time.Minute)
+	select {
+	case entry := <-delivered:
+		if entry.Attempts != 1 {
+			t.Fatalf("delivered entry has %d failed attempts, want 1", entry.Attempts)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("entry was not redelivered once its backoff passed")
+	}
+}
+
+func TestOutboxGivesUpAfterMaxAttempts(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Time{})
+	client := core.NewClient(failingServer(t, 100).URL, nil)
+	client.Config().Clock = clock
+	store := core.NewMemoryOutboxStore()
+	failed := make(chan *core.OutboxEntry, 1)
+	o := &core.Outbox{
+		Client:      client,
+		Store:       store,
+		HeadersFunc: noHeaders,
+		Backoff:     &core.RetryPolicy{BaseDelay: time.Second},
+		MaxAttempts: 2,
+		OnFailed:    func(entry *core.OutboxEntry, err error) { failed <- entry },
+	}
+	if _, err := o.Enqueue("order-1", http.MethodPost, "/orders", "", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	runOutbox(t, o)
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	select {
+	case entry := <-failed:
+		if entry.Attempts != 2 {
+			t.Fatalf("entry failed after %d attempts, want 2", entry.Attempts)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("entry was not given up on after MaxAttempts")
+	}
+	if entries := pendingEntries(t, store); len(entries) != 0 {
+		t.Fatalf("%d entries left after giving up, want 0", len(entries))
+	}
+}
+
+func TestOutboxHoldsAmbiguousPostForReconciliation(t *testing.T) {
+	var received atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if received.Add(1) == 1 {
+			// Drop the connection after the request arrived, so the client
+			// cannot tell whether it was processed.
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	store := core.NewMemoryOutboxStore()
+	reconcile := make(chan *core.OutboxEntry, 1)
+	delivered := make(chan *core.OutboxEntry, 1)
+	o := &core.Outbox{
+		Client:      core.NewClient(server.URL, nil),
+		Store:       store,
+		HeadersFunc: noHeaders,
+		OnReconcile: func(entry *core.OutboxEntry, err error) { reconcile <- entry },
+		OnDelivered: func(entry *core.OutboxEntry, response []byte) { delivered <- entry },
+		OnFailed: func(entry *core.OutboxEntry, err error) {
+			t.Errorf("entry %s failed: %v, want it held for reconciliation", entry.Id, err)
+		},
+	}
+	if _, err := o.Enqueue("order-1", http.MethodPost, "/orders", "", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	runOutbox(t, o)
+
+	select {
+	case entry := <-reconcile:
+		if !entry.NeedsReconciliation {
+			t.Fatal("entry passed to OnReconcile is not marked NeedsReconciliation")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ambiguous delivery was not passed to OnReconcile")
+	}
+	waitFor(t, "the entry to be stored as needing reconciliation", func() bool {
+		entries := pendingEntries(t, store)
+		return len(entries) == 1 && entries[0].NeedsReconciliation
+	})
+	if n := received.Load(); n != 1 {
+		t.Fatalf("server received %d requests before Resolve, want 1", n)
+	}
+
+	if err := o.Resolve("order-1", true); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-delivered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("resolved entry was not resent")
+	}
+}
@@ -0,0 +1,199 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Paginator walks a cursor-paginated GET endpoint, fetching pages as items
+// are consumed:
+//
+//	p := &core.Paginator[Order]{Client: client, Path: "/orders", CursorParam: "cursor", ItemsField: "orders", CursorField: "pagination.next_cursor"}
+//	for p.Next(ctx) {
+//		process(p.Item())
+//	}
+//	err := p.Err()
+//
+// Iteration ends when a page has no next cursor, HasNextField is false, or
+// the cursor repeats.
+type Paginator[T any] struct {
+	Client      Client
+	Path        string
+	HeadersFunc HeaderFunc
+
+	// Query is sent with every page, e.g. "?limit=100". The cursor is
+	// appended to it.
+	Query string
+
+	// CursorParam is the query parameter carrying the cursor.
+	CursorParam string
+
+	// ItemsField and CursorField locate the items array and the next cursor
+	// in a page, as dotted paths such as "pagination.next_cursor". An empty
+	// ItemsField means the page is the array.
+	ItemsField  string
+	CursorField string
+
+	// HasNextField, when set, locates a boolean that is false on the last
+	// page.
+	HasNextField string
+
+	// Extract, when set, replaces the fields and returns a page's items and
+	// next cursor, "" on the last page.
+	Extract func(body []byte) (items []T, cursor string, err error)
+
+	page    []T
+	item    T
+	cursor  string
+	fetched bool
+	done    bool
+	err     error
+	seen    map[string]bool
+}
+
+// Next advances to the next item, fetching a page when needed, and reports
+// whether there is one.
+func (p *Paginator[T]) Next(ctx context.Context) bool {
+	for len(p.page) == 0 {
+		if p.done || p.err != nil {
+			return false
+		}
+		p.fetch(ctx)
+	}
+
+	p.item = p.page[0]
+	p.page = p.page[1:]
+	return true
+}
+
+// Item returns the current item.
+func (p *Paginator[T]) Item() T {
+	return p.item
+}
+
+// Err returns the error that ended iteration, if any.
+func (p *Paginator[T]) Err() error {
+	return p.err
+}
+
+// All collects the remaining items.
+func (p *Paginator[T]) All(ctx context.Context) ([]T, error) {
+	var items []T
+	for p.Next(ctx) {
+		items = append(items, p.Item())
+	}
+	return items, p.Err()
+}
+
+func (p *Paginator[T]) fetch(ctx context.Context) {
+	query := p.Query
+	if p.fetched && p.CursorParam != "" {
+		query = AppendHttpQueryParam(query, p.CursorParam, p.cursor)
+	}
+
+	var body []byte
+	if err := HttpGet(ctx, p.Client, p.Path, query, nil, &body, p.HeadersFunc); err != nil {
+		p.err = err
+		return
+	}
+	p.fetched = true
+
+	items, next, err := p.extract(body)
+	if err != nil {
+		p.err = err
+		return
+	}
+	p.page = items
+
+	if p.seen == nil {
+		p.seen = map[string]bool{}
+	}
+	if next == "" || p.seen[next] {
+		p.done = true
+		return
+	}
+	p.seen[next] = true
+	p.cursor = next
+}
+
+func (p *Paginator[T]) extract(body []byte) ([]T, string, error) {
+	if p.Extract != nil {
+		return p.Extract(body)
+	}
+
+	var items []T
+	raw, err := jsonField(body, p.ItemsField)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(raw) > 0 && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, "", fmt.Errorf("decoding %q: %w", p.ItemsField, err)
+		}
+	}
+
+	if p.HasNextField != "" {
+		var hasNext bool
+		raw, err := jsonField(body, p.HasNextField)
+		if err != nil {
+			return nil, "", err
+		}
+		if len(raw) > 0 && json.Unmarshal(raw, &hasNext) == nil && !hasNext {
+			return items, "", nil
+		}
+	}
+
+	if p.CursorField == "" {
+		return items, "", nil
+	}
+	raw, err = jsonField(body, p.CursorField)
+	if err != nil {
+		return nil, "", err
+	}
+	var cursor string
+	if len(raw) > 0 && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &cursor); err != nil {
+			return nil, "", fmt.Errorf("decoding %q: %w", p.CursorField, err)
+		}
+	}
+	return items, cursor, nil
+}
+
+// jsonField returns the value at a dotted path within body, nil when a
+// segment is missing, or body itself for an empty path.
+func jsonField(body []byte, path string) (json.RawMessage, error) {
+	raw := json.RawMessage(body)
+	if path == "" {
+		return raw, nil
+	}
+
+	for _, segment := range strings.Split(path, ".") {
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &object); err != nil {
+			return nil, fmt.Errorf("decoding page to find %q: %w", path, err)
+		}
+		var ok bool
+		if raw, ok = object[segment]; !ok {
+			return nil, nil
+		}
+	}
+	return raw, nil
+}
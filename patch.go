@@ -0,0 +1,205 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// JsonPatchOperation is one RFC 6902 operation.
+type JsonPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// MergePatch returns the RFC 7386 merge patch turning before into after,
+// both encoded as JSON first. Fields removed in after are sent as null, so a
+// field cannot be set to null itself; use JsonPatch for that.
+func MergePatch(before, after interface{}) (json.RawMessage, error) {
+	b, a, err := toJsonValues(before, after)
+	if err != nil {
+		return nil, err
+	}
+
+	patch, ok := mergeDiff(b, a)
+	if !ok {
+		patch = map[string]interface{}{}
+	}
+	return json.Marshal(patch)
+}
+
+// mergeDiff returns the merge patch from b to a, and false when they are
+// equal.
+func mergeDiff(b, a interface{}) (interface{}, bool) {
+	bObject, bOk := b.(map[string]interface{})
+	aObject, aOk := a.(map[string]interface{})
+	if !bOk || !aOk {
+		if reflect.DeepEqual(b, a) {
+			return nil, false
+		}
+		return a, true
+	}
+
+	patch := map[string]interface{}{}
+	for key := range bObject {
+		if _, ok := aObject[key]; !ok {
+			patch[key] = nil
+		}
+	}
+	for key, value := range aObject {
+		previous, ok := bObject[key]
+		if !ok {
+			patch[key] = value
+			continue
+		}
+		if diff, changed := mergeDiff(previous, value); changed {
+			patch[key] = diff
+		}
+	}
+	return patch, len(patch) > 0
+}
+
+// JsonPatch returns the RFC 6902 operations turning before into after, both
+// encoded as JSON first. Changed arrays are replaced whole.
+func JsonPatch(before, after interface{}) ([]JsonPatchOperation, error) {
+	b, a, err := toJsonValues(before, after)
+	if err != nil {
+		return nil, err
+	}
+
+	operations := []JsonPatchOperation{}
+	jsonPatchDiff(b, a, "", &operations)
+	return operations, nil
+}
+
+func jsonPatchDiff(b, a interface{}, path string, operations *[]JsonPatchOperation) {
+	bObject, bOk := b.(map[string]interface{})
+	aObject, aOk := a.(map[string]interface{})
+	if !bOk || !aOk {
+		if !reflect.DeepEqual(b, a) {
+			*operations = append(*operations, JsonPatchOperation{Op: "replace", Path: path, Value: a})
+		}
+		return
+	}
+
+	for _, key := range sortedKeys(bObject) {
+		if _, ok := aObject[key]; !ok {
+			*operations = append(*operations, JsonPatchOperation{Op: "remove", Path: path + "/" + escapeJsonPointer(key)})
+		}
+	}
+	for _, key := range sortedKeys(aObject) {
+		child := path + "/" + escapeJsonPointer(key)
+		previous, ok := bObject[key]
+		if !ok {
+			*operations = append(*operations, JsonPatchOperation{Op: "add", Path: child, Value: aObject[key]})
+			continue
+		}
+		jsonPatchDiff(previous, aObject[key], child, operations)
+	}
+}
+
+// MarshalJSON leaves out the value of remove operations and keeps an
+// explicit null value for the others.
+func (o JsonPatchOperation) MarshalJSON() ([]byte, error) {
+	if o.Op == "remove" {
+		return json.Marshal(struct {
+			Op   string `json:"op"`
+			Path string `json:"path"`
+		}{o.Op, o.Path})
+	}
+	return json.Marshal(struct {
+		Op    string      `json:"op"`
+		Path  string      `json:"path"`
+		Value interface{} `json:"value"`
+	}{o.Op, o.Path, o.Value})
+}
+
+// HttpMergePatch sends the merge patch from before to after as a PATCH with
+// the application/merge-patch+json content type.
+func HttpMergePatch(
+	ctx context.Context,
+	client Client,
+	path,
+	query string,
+	before,
+	after,
+	response interface{},
+	headersFunc HeaderFunc,
+) error {
+	patch, err := MergePatch(before, after)
+	if err != nil {
+		return err
+	}
+	return HttpPatch(WithContentType(ctx, ContentTypeMergePatch), client, path, query, patch, response, headersFunc)
+}
+
+// HttpJsonPatch sends the JSON patch from before to after as a PATCH with
+// the application/json-patch+json content type.
+func HttpJsonPatch(
+	ctx context.Context,
+	client Client,
+	path,
+	query string,
+	before,
+	after,
+	response interface{},
+	headersFunc HeaderFunc,
+) error {
+	operations, err := JsonPatch(before, after)
+	if err != nil {
+		return err
+	}
+	return HttpPatch(WithContentType(ctx, ContentTypeJsonPatch), client, path, query, operations, response, headersFunc)
+}
+
+// toJsonValues round trips before and after through JSON, so struct tags,
+// omitempty and custom marshalers decide what is compared.
+func toJsonValues(before, after interface{}) (interface{}, interface{}, error) {
+	var values [2]interface{}
+	for i, v := range []interface{}{before, after} {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, nil, err
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&values[i]); err != nil {
+			return nil, nil, err
+		}
+	}
+	return values[0], values[1], nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// escapeJsonPointer escapes a key for use in an RFC 6901 JSON pointer.
+func escapeJsonPointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
@@ -0,0 +1,199 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"sync"
+)
+
+// Priority classifies a call for scheduling. Untagged calls are PriorityNormal.
+type Priority int
+
+const (
+	PriorityBackground Priority = -1
+	PriorityNormal     Priority = 0
+	PriorityCritical   Priority = 1
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityBackground:
+		return "background"
+	case PriorityNormal:
+		return "normal"
+	case PriorityCritical:
+		return "critical"
+	default:
+		return "unknown"
+	}
+}
+
+type priorityKey struct{}
+
+// WithPriority tags calls made with the returned context with p.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFromContext returns the priority set by WithPriority, or
+// PriorityNormal.
+func PriorityFromContext(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+	return PriorityNormal
+}
+
+// priorityRank maps a priority to a queue index, highest priority first.
+func priorityRank(p Priority) int {
+	switch {
+	case p >= PriorityCritical:
+		return 0
+	case p <= PriorityBackground:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// Scheduler bounds the number of concurrent calls. Waiting calls are admitted
+// highest priority first, in arrival order within a class, and a number of
+// slots can be held back so critical calls (e.g., order cancels) never queue
+// behind normal or background traffic.
+type Scheduler struct {
+	mu       sync.Mutex
+	capacity int
+	reserved int
+	inUse    int
+	queues   [3][]chan struct{}
+}
+
+// NewScheduler returns a Scheduler admitting up to capacity concurrent calls,
+// of which reservedForCritical are only available to PriorityCritical calls.
+func NewScheduler(capacity, reservedForCritical int) *Scheduler {
+	if capacity < 1 {
+		capacity = 1
+	}
+	if reservedForCritical < 0 {
+		reservedForCritical = 0
+	}
+	if reservedForCritical >= capacity {
+		reservedForCritical = capacity - 1
+	}
+	return &Scheduler{capacity: capacity, reserved: reservedForCritical}
+}
+
+// Acquire blocks until a call with priority p may proceed or ctx is done. The
+// returned release function must be called once the call completes.
+func (s *Scheduler) Acquire(ctx context.Context, p Priority) (release func(), err error) {
+	rank := priorityRank(p)
+
+	s.mu.Lock()
+	if s.waitingAtOrAbove(rank) == 0 && s.canAdmit(rank) {
+		s.inUse++
+		s.mu.Unlock()
+		return s.releaser(), nil
+	}
+
+	ready := make(chan struct{})
+	s.queues[rank] = append(s.queues[rank], ready)
+	s.mu.Unlock()
+
+	select {
+	case <-ready:
+		return s.releaser(), nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		removed := s.remove(rank, ready)
+		if removed {
+			s.dispatch()
+		}
+		s.mu.Unlock()
+		if !removed {
+			// Admitted concurrently with cancellation; hand the slot back.
+			s.releaser()()
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// InUse returns the number of admitted calls that have not been released.
+func (s *Scheduler) InUse() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inUse
+}
+
+// Waiting returns the number of calls queued for admission.
+func (s *Scheduler) Waiting() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.waitingAtOrAbove(len(s.queues) - 1)
+}
+
+func (s *Scheduler) releaser() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.inUse--
+			s.dispatch()
+		})
+	}
+}
+
+func (s *Scheduler) canAdmit(rank int) bool {
+	if rank == 0 {
+		return s.inUse < s.capacity
+	}
+	return s.inUse < s.capacity-s.reserved
+}
+
+func (s *Scheduler) waitingAtOrAbove(rank int) int {
+	n := 0
+	for i := 0; i <= rank; i++ {
+		n += len(s.queues[i])
+	}
+	return n
+}
+
+func (s *Scheduler) dispatch() {
+	for rank := range s.queues {
+		for len(s.queues[rank]) > 0 {
+			if !s.canAdmit(rank) {
+				return
+			}
+			ready := s.queues[rank][0]
+			s.queues[rank] = s.queues[rank][1:]
+			s.inUse++
+			close(ready)
+		}
+	}
+}
+
+func (s *Scheduler) remove(rank int, ready chan struct{}) bool {
+	queue := s.queues[rank]
+	for i, c := range queue {
+		if c == ready {
+			s.queues[rank] = append(queue[:i], queue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,181 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ProbeResult is the handshake time to one candidate endpoint.
+type ProbeResult struct {
+	Url string
+	Rtt time.Duration
+	Err error
+}
+
+// ProbeEndpoints measures the TCP, and for https and wss the TLS, handshake
+// time to each URL concurrently. Results are ordered fastest first, with
+// failures last.
+func ProbeEndpoints(ctx context.Context, urls []string, timeout time.Duration) []ProbeResult {
+	results := make([]ProbeResult, len(urls))
+
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		wg.Add(1)
+		go func(i int, u string) {
+			defer wg.Done()
+			rtt, err := probeEndpoint(ctx, u, timeout)
+			results[i] = ProbeResult{Url: u, Rtt: rtt, Err: err}
+		}(i, u)
+	}
+	wg.Wait()
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if (results[i].Err == nil) != (results[j].Err == nil) {
+			return results[i].Err == nil
+		}
+		return results[i].Rtt < results[j].Rtt
+	})
+
+	return results
+}
+
+func probeEndpoint(ctx context.Context, rawUrl string, timeout time.Duration) (time.Duration, error) {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return 0, fmt.Errorf("invalid endpoint url: %s - %w", rawUrl, err)
+	}
+
+	secure := u.Scheme == "https" || u.Scheme == "wss"
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if secure {
+			port = "443"
+		}
+	}
+	address := net.JoinHostPort(u.Hostname(), port)
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	dialer := &net.Dialer{}
+	start := time.Now()
+
+	var conn net.Conn
+	if secure {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: u.Hostname()}}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	rtt := time.Since(start)
+	conn.Close()
+	return rtt, nil
+}
+
+// EndpointSelector keeps track of the fastest of a set of candidate base URLs.
+// A Client can return Best from HttpBaseUrl to follow the selection.
+type EndpointSelector struct {
+	// Clock schedules Run. Defaults to SystemClock; a TickerClock such as
+	// testutil.FakeClock makes Run deterministic in tests. Handshake times
+	// are always measured on the wall clock.
+	Clock Clock
+
+	urls    []string
+	timeout time.Duration
+
+	mu      sync.RWMutex
+	best    string
+	results []ProbeResult
+}
+
+// NewEndpointSelector returns a selector that initially picks the first URL.
+func NewEndpointSelector(urls []string, timeout time.Duration) *EndpointSelector {
+	s := &EndpointSelector{
+		urls:    append([]string(nil), urls...),
+		timeout: timeout,
+	}
+	if len(urls) > 0 {
+		s.best = urls[0]
+	}
+	return s
+}
+
+// Best returns the fastest URL from the last successful probe.
+func (s *EndpointSelector) Best() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.best
+}
+
+// Results returns the outcome of the last probe.
+func (s *EndpointSelector) Results() []ProbeResult {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]ProbeResult(nil), s.results...)
+}
+
+// Probe measures every candidate and selects the fastest. The selection is
+// unchanged when every candidate fails.
+func (s *EndpointSelector) Probe(ctx context.Context) error {
+	results := ProbeEndpoints(ctx, s.urls, s.timeout)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.results = results
+	if len(results) == 0 || results[0].Err != nil {
+		return errors.New("no endpoint could be reached")
+	}
+	s.best = results[0].Url
+	return nil
+}
+
+// Run probes immediately and then every interval until ctx is done. Probe
+// failures are passed to onError when it is set.
+func (s *EndpointSelector) Run(ctx context.Context, interval time.Duration, onError func(err error)) {
+	ticker := newTicker(s.Clock, interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Probe(ctx); err != nil && onError != nil && ctx.Err() == nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
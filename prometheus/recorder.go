@@ -0,0 +1,97 @@
+//go:build prometheus
+
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package prometheus provides a core.MetricsRecorder exporting request
+// counts, latency histograms, and retries to Prometheus.
+package prometheus
+
+import (
+	"strconv"
+	"time"
+
+	core "github.com/coinbase-samples/core-go"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+var labels = []string{"method", "path", "status"}
+
+// Recorder records each attempt as <namespace>_http_requests_total and
+// <namespace>_http_request_duration_seconds, and each retry as
+// <namespace>_http_retries_total, labeled by method, path, and status.
+type Recorder struct {
+	requests *prom.CounterVec
+	duration *prom.HistogramVec
+	retries  *prom.CounterVec
+}
+
+var (
+	_ core.MetricsRecorder = (*Recorder)(nil)
+	_ core.RetryRecorder   = (*Recorder)(nil)
+)
+
+// NewRecorder registers the metrics with registerer, or
+// prom.DefaultRegisterer when nil. The histogram uses
+// core.DefaultLatencyBuckets unless buckets are given.
+func NewRecorder(namespace string, registerer prom.Registerer, buckets ...time.Duration) (*Recorder, error) {
+	if registerer == nil {
+		registerer = prom.DefaultRegisterer
+	}
+	if len(buckets) == 0 {
+		buckets = core.DefaultLatencyBuckets
+	}
+	seconds := make([]float64, len(buckets))
+	for i, b := range buckets {
+		seconds[i] = b.Seconds()
+	}
+
+	r := &Recorder{
+		requests: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "API call attempts.",
+		}, labels),
+		duration: prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "API call latency per attempt.",
+			Buckets:   seconds,
+		}, labels),
+		retries: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_retries_total",
+			Help:      "Retried API call attempts, by the status of the failed attempt.",
+		}, labels),
+	}
+
+	for _, c := range []prom.Collector{r.requests, r.duration, r.retries} {
+		if err := registerer.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func (r *Recorder) RecordRequest(httpMethod, path string, statusCode int, duration time.Duration) {
+	status := strconv.Itoa(statusCode)
+	r.requests.WithLabelValues(httpMethod, path, status).Inc()
+	r.duration.WithLabelValues(httpMethod, path, status).Observe(duration.Seconds())
+}
+
+func (r *Recorder) RecordRetry(httpMethod, path string, statusCode, attempt int) {
+	r.retries.WithLabelValues(httpMethod, path, strconv.Itoa(statusCode)).Inc()
+}
@@ -0,0 +1,190 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CredentialsProvider supplies credentials, e.g. from the environment, a
+// file, or a secrets manager.
+type CredentialsProvider interface {
+	Retrieve(ctx context.Context) (*Credentials, error)
+}
+
+// CredentialsProviderFunc adapts a function to a CredentialsProvider.
+type CredentialsProviderFunc func(ctx context.Context) (*Credentials, error)
+
+func (f CredentialsProviderFunc) Retrieve(ctx context.Context) (*Credentials, error) {
+	return f(ctx)
+}
+
+// EnvCredentialsProvider reads credentials JSON from the environment variable
+// varName on every Retrieve.
+func EnvCredentialsProvider(varName string) CredentialsProvider {
+	return CredentialsProviderFunc(func(ctx context.Context) (*Credentials, error) {
+		return ReadCredentialsFromEnv(varName)
+	})
+}
+
+// FileCredentialsProvider reads the credentials JSON file at path on every
+// Retrieve, so replacing the file rotates the key.
+func FileCredentialsProvider(path string) CredentialsProvider {
+	return CredentialsProviderFunc(func(ctx context.Context) (*Credentials, error) {
+		return ReadCredentialsFromFile(path)
+	})
+}
+
+// ChainCredentialsProvider tries each provider in order and returns the
+// first credentials found, e.g. environment, then file, then a custom source.
+type ChainCredentialsProvider []CredentialsProvider
+
+func (c ChainCredentialsProvider) Retrieve(ctx context.Context) (*Credentials, error) {
+	var errs []error
+	for _, provider := range c {
+		credentials, err := provider.Retrieve(ctx)
+		if err == nil {
+			return credentials, nil
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return nil, errors.New("no credentials providers configured")
+	}
+	return nil, errors.Join(errs...)
+}
+
+// RefreshingCredentials holds the current credentials from a provider and
+// the signer built from them, so a long-running service can rotate keys
+// without restarting. Refresh, or Run in the background, picks up changes;
+// a failed refresh keeps the previous credentials.
+type RefreshingCredentials struct {
+	provider CredentialsProvider
+
+	mu          sync.RWMutex
+	credentials *Credentials
+	signer      Signer
+	refreshedAt time.Time
+}
+
+func NewRefreshingCredentials(provider CredentialsProvider) *RefreshingCredentials {
+	return &RefreshingCredentials{provider: provider}
+}
+
+// Refresh retrieves the credentials and rebuilds the signer when they
+// changed.
+func (r *RefreshingCredentials) Refresh(ctx context.Context) error {
+	credentials, err := r.provider.Retrieve(ctx)
+	if err != nil {
+		return err
+	}
+
+	r.mu.RLock()
+	current := r.credentials
+	r.mu.RUnlock()
+
+	if current != nil && *current == *credentials {
+		r.mu.Lock()
+		r.refreshedAt = time.Now()
+		r.mu.Unlock()
+		return nil
+	}
+
+	signer, err := NewSigner(credentials)
+	if err != nil {
+		return err
+	}
+	if current != nil {
+		// Tokens minted with a replaced key must not outlive it.
+		DefaultJwtTokenCache.Invalidate(current.AccessKey)
+	}
+
+	r.mu.Lock()
+	r.credentials, r.signer, r.refreshedAt = credentials, signer, time.Now()
+	r.mu.Unlock()
+	return nil
+}
+
+// Run refreshes every interval until ctx is done. Failures are passed to
+// onError when it is set.
+func (r *RefreshingCredentials) Run(ctx context.Context, interval time.Duration, onError func(err error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Refresh(ctx); err != nil && onError != nil && ctx.Err() == nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// Retrieve returns the current credentials, loading them on first use, so
+// RefreshingCredentials is itself a CredentialsProvider.
+func (r *RefreshingCredentials) Retrieve(ctx context.Context) (*Credentials, error) {
+	r.mu.RLock()
+	credentials := r.credentials
+	r.mu.RUnlock()
+
+	if credentials != nil {
+		copied := *credentials
+		return &copied, nil
+	}
+	if err := r.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	return r.Retrieve(ctx)
+}
+
+// RefreshedAt returns when the credentials were last retrieved.
+func (r *RefreshingCredentials) RefreshedAt() time.Time {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.refreshedAt
+}
+
+// HeaderFunc returns a HeaderFunc that signs with the current credentials,
+// loading them on first use. If none can be loaded, or signing fails, the
+// call fails with a *SigningError.
+func (r *RefreshingCredentials) HeaderFunc() HeaderFunc {
+	return func(req *http.Request, path string, body []byte, client Client, t time.Time) {
+		r.mu.RLock()
+		signer := r.signer
+		r.mu.RUnlock()
+
+		if signer == nil {
+			if err := r.Refresh(req.Context()); err != nil {
+				FailSigning(req, err)
+				return
+			}
+			r.mu.RLock()
+			signer = r.signer
+			r.mu.RUnlock()
+		}
+		if err := signRequest(signer, req, path, body, t); err != nil {
+			FailSigning(req, err)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// ErrProxyConnect is matched with errors.Is by every *ProxyConnectError.
+var ErrProxyConnect = errors.New("proxy CONNECT failed")
+
+// ProxyConnectError reports that a tunnel through a proxy could not be
+// opened, so a proxy failure, such as a 407 for missing proxy credentials,
+// can be told apart from a rejection by the API behind it.
+type ProxyConnectError struct {
+	// ProxyUrl is the proxy, with any credentials removed.
+	ProxyUrl string
+
+	// Target is the host:port the tunnel was requested for.
+	Target string
+
+	// StatusCode, Status, and Header are the proxy's answer to CONNECT. They
+	// are empty when the proxy could not be reached or did not answer.
+	StatusCode int
+	Status     string
+	Header     http.Header
+
+	// Err is the transport error when the proxy did not answer.
+	Err error
+}
+
+func (e *ProxyConnectError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("%v: proxy %s, target %s: %v", ErrProxyConnect, e.ProxyUrl, e.Target, e.Err)
+	}
+	return fmt.Sprintf("%v: proxy %s, target %s: %s", ErrProxyConnect, e.ProxyUrl, e.Target, e.Status)
+}
+
+func (e *ProxyConnectError) Unwrap() error        { return e.Err }
+func (e *ProxyConnectError) Is(target error) bool { return target == ErrProxyConnect }
+
+// ProxyDialer opens connections through an HTTP or HTTPS proxy with CONNECT.
+// Its DialContext fits the NetDialContext field of websocket dialers such as
+// gorilla's, and http.Transport's DialContext, so failures come back as a
+// *ProxyConnectError instead of a bare handshake error.
+type ProxyDialer struct {
+	// ProxyUrl is the proxy. Credentials in it are sent as
+	// Proxy-Authorization with basic auth.
+	ProxyUrl *url.URL
+
+	// Header holds extra headers for the CONNECT request.
+	Header http.Header
+
+	// Dialer connects to the proxy. Defaults to a zero net.Dialer.
+	Dialer *net.Dialer
+}
+
+// DialContext returns a connection to addr tunneled through the proxy.
+func (d *ProxyDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	proxyAddr := d.proxyAddr()
+	fail := func(err error, res *http.Response) error {
+		connectErr := &ProxyConnectError{ProxyUrl: d.redactedUrl(), Target: addr, Err: err}
+		if res != nil {
+			connectErr.StatusCode = res.StatusCode
+			connectErr.Status = res.Status
+			connectErr.Header = res.Header
+		}
+		return connectErr
+	}
+
+	dialer := d.Dialer
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, fail(err, nil)
+	}
+
+	// Abort the handshake with the proxy when ctx ends.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if d.ProxyUrl.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: d.ProxyUrl.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fail(err, nil)
+		}
+		conn = tlsConn
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: d.Header.Clone(),
+	}
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	if user := d.ProxyUrl.User; user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fail(err, nil)
+	}
+	reader := bufio.NewReader(conn)
+	res, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, fail(err, nil)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fail(nil, res)
+	}
+
+	if !stop() {
+		conn.Close()
+		return nil, fail(ctx.Err(), nil)
+	}
+	if reader.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+func (d *ProxyDialer) proxyAddr() string {
+	if port := d.ProxyUrl.Port(); port != "" {
+		return d.ProxyUrl.Host
+	}
+	if d.ProxyUrl.Scheme == "https" {
+		return net.JoinHostPort(d.ProxyUrl.Hostname(), "443")
+	}
+	return net.JoinHostPort(d.ProxyUrl.Hostname(), "80")
+}
+
+func (d *ProxyDialer) redactedUrl() string {
+	redacted := *d.ProxyUrl
+	redacted.User = nil
+	return redacted.String()
+}
+
+// bufferedConn reads bytes the proxy sent after its CONNECT response before
+// reading from the connection itself.
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
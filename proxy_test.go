@@ -0,0 +1,149 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// startConnectProxy runs a proxy that requires basic auth for user:secret
+// and tunnels accepted CONNECTs to their target.
+func startConnectProxy(t *testing.T) *url.URL {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if user, password, ok := parseProxyAuth(r.Header.Get("Proxy-Authorization")); !ok || user != "user" || password != "secret" {
+			w.Header().Set("Proxy-Authenticate", `Basic realm="proxy"`)
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+
+		target, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		client, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			target.Close()
+			return
+		}
+		client.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n"))
+		go func() {
+			io.Copy(target, client)
+			target.Close()
+		}()
+		io.Copy(client, target)
+		client.Close()
+	}))
+	t.Cleanup(server.Close)
+
+	proxyUrl, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return proxyUrl
+}
+
+func parseProxyAuth(header string) (string, string, bool) {
+	req := &http.Request{Header: http.Header{"Authorization": {header}}}
+	return req.BasicAuth()
+}
+
+// startEchoServer echoes one line per connection.
+func startEchoServer(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestProxyDialerTunnels(t *testing.T) {
+	proxyUrl := startConnectProxy(t)
+	proxyUrl.User = url.UserPassword("user", "secret")
+	target := startEchoServer(t)
+
+	conn, err := (&ProxyDialer{ProxyUrl: proxyUrl}).DialContext(context.Background(), "tcp", target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	echoed := make([]byte, 4)
+	if _, err := io.ReadFull(conn, echoed); err != nil || string(echoed) != "ping" {
+		t.Fatalf("read %q, %v through the tunnel, want ping", echoed, err)
+	}
+}
+
+func TestProxyDialerReportsConnectRejection(t *testing.T) {
+	proxyUrl := startConnectProxy(t)
+	proxyUrl.User = url.UserPassword("user", "wrong")
+
+	_, err := (&ProxyDialer{ProxyUrl: proxyUrl}).DialContext(context.Background(), "tcp", "api.coinbase.com:443")
+	var connectErr *ProxyConnectError
+	if !errors.As(err, &connectErr) || !errors.Is(err, ErrProxyConnect) {
+		t.Fatalf("err = %v, want a *ProxyConnectError", err)
+	}
+	if connectErr.StatusCode != http.StatusProxyAuthRequired || connectErr.Header.Get("Proxy-Authenticate") == "" {
+		t.Fatalf("error = %+v, want the 407 and its Proxy-Authenticate header", connectErr)
+	}
+	if connectErr.Target != "api.coinbase.com:443" || strings.Contains(connectErr.ProxyUrl, "wrong") || strings.Contains(err.Error(), "wrong") {
+		t.Fatalf("error = %+v (%v), want the target and the proxy URL without credentials", connectErr, err)
+	}
+}
+
+func TestProxyDialerReportsUnreachableProxy(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	_, err = (&ProxyDialer{ProxyUrl: &url.URL{Scheme: "http", Host: addr}}).DialContext(context.Background(), "tcp", "api.coinbase.com:443")
+	var connectErr *ProxyConnectError
+	if !errors.As(err, &connectErr) || connectErr.StatusCode != 0 || connectErr.Err == nil {
+		t.Fatalf("err = %v, want a *ProxyConnectError carrying the dial error", err)
+	}
+}
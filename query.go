@@ -0,0 +1,114 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// AppendHttpQueryParam appends key=value to a query string of the form
+// "?a=1&b=2", starting one when query is empty. The value is escaped.
+func AppendHttpQueryParam(query, key, value string) string {
+	escaped := url.QueryEscape(value)
+
+	var b strings.Builder
+	b.Grow(len(query) + len(key) + len(escaped) + 2)
+	b.WriteString(query)
+	writeQuerySeparator(&b, len(query) == 0)
+	b.WriteString(key)
+	b.WriteByte('=')
+	b.WriteString(escaped)
+	return b.String()
+}
+
+// QueryBuilder builds a query string of the form "?a=1&b=2" in a single
+// buffer. The zero value is ready to use.
+type QueryBuilder struct {
+	b strings.Builder
+}
+
+// NewQueryBuilder returns a QueryBuilder whose buffer is pre-sized for sizeHint
+// bytes.
+func NewQueryBuilder(sizeHint int) *QueryBuilder {
+	q := &QueryBuilder{}
+	q.b.Grow(sizeHint)
+	return q
+}
+
+// Add appends key=value, escaping the value.
+func (q *QueryBuilder) Add(key, value string) *QueryBuilder {
+	writeQuerySeparator(&q.b, q.b.Len() == 0)
+	q.b.WriteString(key)
+	q.b.WriteByte('=')
+	q.b.WriteString(url.QueryEscape(value))
+	return q
+}
+
+// AddIfNotEmpty appends key=value only when value is not empty.
+func (q *QueryBuilder) AddIfNotEmpty(key, value string) *QueryBuilder {
+	if value == "" {
+		return q
+	}
+	return q.Add(key, value)
+}
+
+// AddValues appends key once per value, e.g. "product_ids=a&product_ids=b".
+func (q *QueryBuilder) AddValues(key string, values []string) *QueryBuilder {
+	for _, v := range values {
+		q.Add(key, v)
+	}
+	return q
+}
+
+// String returns the query, or EmptyQueryParams when nothing was added.
+func (q *QueryBuilder) String() string {
+	return q.b.String()
+}
+
+// EncodeQueryValues encodes values, sorted by key, as a query string of the
+// form "?a=1&b=2", or EmptyQueryParams when values is empty.
+func EncodeQueryValues(values url.Values) string {
+	if len(values) == 0 {
+		return EmptyQueryParams
+	}
+
+	keys := make([]string, 0, len(values))
+	size := 0
+	for k, vs := range values {
+		keys = append(keys, k)
+		for _, v := range vs {
+			size += len(k) + len(v) + 2
+		}
+	}
+	sort.Strings(keys)
+
+	q := NewQueryBuilder(size)
+	for _, k := range keys {
+		q.AddValues(url.QueryEscape(k), values[k])
+	}
+	return q.String()
+}
+
+func writeQuerySeparator(b *strings.Builder, first bool) {
+	if first {
+		b.WriteByte('?')
+	} else {
+		b.WriteByte('&')
+	}
+}
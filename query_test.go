@@ -0,0 +1,106 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"net/url"
+	"testing"
+)
+
+var benchmarkQuery string
+
+func TestQueryBuilderMatchesAppend(t *testing.T) {
+	appended := AppendHttpQueryParam(EmptyQueryParams, "product_id", "BTC-USD")
+	appended = AppendHttpQueryParam(appended, "limit", "100")
+	appended = AppendHttpQueryParam(appended, "cursor", "a b&c")
+
+	built := NewQueryBuilder(0).
+		Add("product_id", "BTC-USD").
+		Add("limit", "100").
+		AddIfNotEmpty("start", "").
+		Add("cursor", "a b&c").
+		String()
+
+	want := "?product_id=BTC-USD&limit=100&cursor=a+b%26c"
+	if appended != want || built != want {
+		t.Fatalf("append = %q, builder = %q, want %q", appended, built, want)
+	}
+}
+
+func BenchmarkAppendHttpQueryParam(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		q := AppendHttpQueryParam(EmptyQueryParams, "product_id", "BTC-USD")
+		q = AppendHttpQueryParam(q, "limit", "100")
+		q = AppendHttpQueryParam(q, "order_status", "OPEN")
+		benchmarkQuery = AppendHttpQueryParam(q, "cursor", "abc123")
+	}
+}
+
+func BenchmarkQueryBuilder(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchmarkQuery = (&QueryBuilder{}).
+			Add("product_id", "BTC-USD").
+			Add("limit", "100").
+			Add("order_status", "OPEN").
+			Add("cursor", "abc123").
+			String()
+	}
+}
+
+func BenchmarkQueryBuilderPresized(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchmarkQuery = NewQueryBuilder(64).
+			Add("product_id", "BTC-USD").
+			Add("limit", "100").
+			Add("order_status", "OPEN").
+			Add("cursor", "abc123").
+			String()
+	}
+}
+
+func BenchmarkEncodeQueryValues(b *testing.B) {
+	values := url.Values{
+		"product_id":   {"BTC-USD"},
+		"limit":        {"100"},
+		"order_status": {"OPEN", "PENDING"},
+		"cursor":       {"abc123"},
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchmarkQuery = EncodeQueryValues(values)
+	}
+}
+
+// BenchmarkUrlValuesEncode is the standard library baseline for
+// BenchmarkEncodeQueryValues.
+func BenchmarkUrlValuesEncode(b *testing.B) {
+	values := url.Values{
+		"product_id":   {"BTC-USD"},
+		"limit":        {"100"},
+		"order_status": {"OPEN", "PENDING"},
+		"cursor":       {"abc123"},
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchmarkQuery = "?" + values.Encode()
+	}
+}
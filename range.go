@@ -0,0 +1,177 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrResourceChanged is returned when a download cannot resume because the
+// resource changed since it started.
+var ErrResourceChanged = errors.New("resource changed during download")
+
+// GetRange is GetStream for the bytes of path from offset on. ifRange, when
+// set, is an entity tag or HTTP date the resource must still match; if it does
+// not, the server answers 200 with the whole resource. A server that ignores
+// ranges also answers 200, so callers must check the status code.
+func GetRange(
+	ctx context.Context,
+	client Client,
+	path,
+	query string,
+	offset int64,
+	ifRange string,
+	headersFunc HeaderFunc,
+) (*http.Response, error) {
+	ranged := func(req *http.Request, path string, body []byte, client Client, t time.Time) {
+		if offset > 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+			if ifRange != "" {
+				req.Header.Set("If-Range", ifRange)
+			}
+		}
+		headersFunc(req, path, body, client, t)
+	}
+	return Stream(ctx, client, path, query, http.MethodGet, []int{http.StatusOK, http.StatusPartialContent}, nil, ranged)
+}
+
+// GetResumable streams a GET whose body survives network interruptions: when
+// a read fails, the download is resumed with a Range request from where it
+// stopped, up to maxResumes times. The resource's strong ETag or
+// Last-Modified guards against splicing two versions together, failing with
+// ErrResourceChanged instead. The caller must close the body.
+func GetResumable(ctx context.Context, client Client, path, query string, maxResumes int, headersFunc HeaderFunc) (io.ReadCloser, error) {
+	res, err := GetRange(ctx, client, path, query, 0, "", headersFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resumableBody{
+		ctx:         ctx,
+		client:      client,
+		path:        path,
+		query:       query,
+		headersFunc: headersFunc,
+		maxResumes:  maxResumes,
+		validator:   rangeValidator(res.Header),
+		body:        res.Body,
+	}, nil
+}
+
+type resumableBody struct {
+	ctx         context.Context
+	client      Client
+	path        string
+	query       string
+	headersFunc HeaderFunc
+	maxResumes  int
+	validator   string
+
+	body    io.ReadCloser
+	offset  int64
+	resumes int
+}
+
+func (b *resumableBody) Read(p []byte) (int, error) {
+	for {
+		n, err := b.body.Read(p)
+		b.offset += int64(n)
+		if err == nil || err == io.EOF || n > 0 {
+			if err != nil && err != io.EOF {
+				// Report the bytes read now; the error recurs on the next read.
+				err = nil
+			}
+			return n, err
+		}
+
+		if b.resumes >= b.maxResumes || b.ctx.Err() != nil {
+			return 0, err
+		}
+		b.resumes++
+
+		if resumeErr := b.resume(); resumeErr != nil {
+			return 0, fmt.Errorf("resuming download at byte %d after %v: %w", b.offset, err, resumeErr)
+		}
+	}
+}
+
+func (b *resumableBody) resume() error {
+	b.body.Close()
+	b.body = http.NoBody
+
+	res, err := GetRange(b.ctx, b.client, b.path, b.query, b.offset, b.validator, b.headersFunc)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode == http.StatusPartialContent {
+		if start, ok := contentRangeStart(res.Header.Get("Content-Range")); !ok || start != b.offset {
+			res.Body.Close()
+			return fmt.Errorf("server resumed at %q, expected byte %d", res.Header.Get("Content-Range"), b.offset)
+		}
+		b.body = res.Body
+		return nil
+	}
+
+	// A full body after If-Range means the resource changed; without a
+	// validator the server simply ignored the range.
+	if b.validator != "" {
+		res.Body.Close()
+		return ErrResourceChanged
+	}
+	if _, err := io.CopyN(io.Discard, res.Body, b.offset); err != nil {
+		res.Body.Close()
+		return err
+	}
+	b.body = res.Body
+	return nil
+}
+
+func (b *resumableBody) Close() error {
+	return b.body.Close()
+}
+
+// rangeValidator returns the If-Range value for a response: its strong ETag,
+// or its Last-Modified date. Weak ETags cannot be used with ranges.
+func rangeValidator(header http.Header) string {
+	if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return header.Get("Last-Modified")
+}
+
+// contentRangeStart parses the first byte position of a Content-Range such
+// as "bytes 100-199/200".
+func contentRangeStart(value string) (int64, bool) {
+	spec, ok := strings.CutPrefix(value, "bytes ")
+	if !ok {
+		return 0, false
+	}
+	first, _, ok := strings.Cut(spec, "-")
+	if !ok {
+		return 0, false
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	return start, err == nil
+}
@@ -0,0 +1,147 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned by a fail-fast RateLimiter when a call is over
+// budget. A RateLimitError from the API also matches it.
+var ErrRateLimited = errors.New("client rate limit exceeded")
+
+// RateLimiter admits calls against a request budget. Wait blocks until the
+// call may proceed, or returns an error to refuse it. ctx carries the call's
+// Priority, read with PriorityFromContext.
+type RateLimiter interface {
+	Wait(ctx context.Context) error
+}
+
+// TokenBucket is a RateLimiter allowing a steady rate of requests with bursts
+// of up to burst requests. PriorityCritical calls are served first: they
+// queue among themselves for the next tokens, while other calls wait for
+// tokens no critical call has claimed, so a cancel never sits behind queued
+// background calls.
+type TokenBucket struct {
+	// FailFast refuses calls over budget with ErrRateLimited instead of
+	// waiting.
+	FailFast bool
+
+	// ReservedForCritical is the number of tokens only PriorityCritical
+	// calls may use. It is capped at one less than the burst.
+	ReservedForCritical int
+
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket returns a full bucket refilling at requestsPerSecond. A
+// burst below 1 is treated as 1.
+func NewTokenBucket(requestsPerSecond float64, burst int) *TokenBucket {
+	if requestsPerSecond <= 0 {
+		panic("core: non-positive rate for NewTokenBucket")
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &TokenBucket{
+		rate:   requestsPerSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait takes a token for a call with the priority in ctx. See WaitPriority.
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	return b.WaitPriority(ctx, PriorityFromContext(ctx))
+}
+
+// WaitPriority takes a token for a call with priority p, waiting for one to
+// accrue unless FailFast is set. A critical call claims the next token as it
+// arrives and gives it back if ctx is done first. Other calls claim nothing
+// while waiting, and take a token only once one is free beyond the reserve
+// and any claimed by critical calls.
+func (b *TokenBucket) WaitPriority(ctx context.Context, p Priority) error {
+	critical := p >= PriorityCritical
+
+	for {
+		b.mu.Lock()
+		b.refill(time.Now())
+
+		floor := 0.0
+		if !critical {
+			floor = b.reserve()
+		}
+		if b.tokens >= floor+1 {
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		}
+		if b.FailFast {
+			b.mu.Unlock()
+			return ErrRateLimited
+		}
+
+		delay := time.Duration((floor + 1 - b.tokens) / b.rate * float64(time.Second))
+		if critical {
+			b.tokens--
+		}
+		b.mu.Unlock()
+
+		if !sleepContext(ctx, delay) {
+			if critical {
+				b.mu.Lock()
+				b.tokens++
+				b.mu.Unlock()
+			}
+			return ctx.Err()
+		}
+		if critical {
+			return nil
+		}
+	}
+}
+
+// Tokens returns the requests that may currently be made without waiting.
+func (b *TokenBucket) Tokens() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(time.Now())
+	return b.tokens
+}
+
+func (b *TokenBucket) reserve() float64 {
+	return math.Min(float64(b.ReservedForCritical), b.burst-1)
+}
+
+// refill adds the tokens accrued since the last call. b.mu must be held.
+func (b *TokenBucket) refill(now time.Time) {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketServesCriticalAheadOfQueuedCalls(t *testing.T) {
+	bucket := NewTokenBucket(10, 1)
+	if err := bucket.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(WithPriority(context.Background(), PriorityBackground))
+	defer cancel()
+	var queued sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		queued.Add(1)
+		go func() {
+			defer queued.Done()
+			bucket.Wait(ctx)
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	start := time.Now()
+	if err := bucket.WaitPriority(context.Background(), PriorityCritical); err != nil {
+		t.Fatal(err)
+	}
+	// Behind five background calls, the critical call would wait 600ms.
+	if waited := time.Since(start); waited > 300*time.Millisecond {
+		t.Fatalf("critical call waited %s behind background calls", waited)
+	}
+
+	cancel()
+	queued.Wait()
+}
+
+func TestTokenBucketReservesTokensForCritical(t *testing.T) {
+	bucket := NewTokenBucket(0.001, 2)
+	bucket.FailFast = true
+	bucket.ReservedForCritical = 1
+
+	if err := bucket.WaitPriority(context.Background(), PriorityNormal); err != nil {
+		t.Fatalf("first normal call = %v, want admitted", err)
+	}
+	if err := bucket.WaitPriority(context.Background(), PriorityNormal); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("normal call into the reserve = %v, want ErrRateLimited", err)
+	}
+	if err := bucket.WaitPriority(context.Background(), PriorityCritical); err != nil {
+		t.Fatalf("critical call = %v, want the reserved token", err)
+	}
+}
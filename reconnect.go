@@ -0,0 +1,115 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ReconnectGovernor paces reconnects across every connection that shares it.
+// Each reconnect waits a fully jittered exponential backoff, and reconnects
+// are additionally spaced so no more than MaxPerSecond start process-wide,
+// preventing a synchronized reconnect storm after a brief outage.
+type ReconnectGovernor struct {
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	MaxPerSecond float64
+
+	// Clock times the waits. Defaults to SystemClock; a TimerClock such as
+	// testutil.FakeClock also drives Wait.
+	Clock Clock
+
+	mu       sync.Mutex
+	nextSlot time.Time
+	rand     *rand.Rand
+}
+
+// DefaultReconnectGovernor is shared by callers that do not supply their own.
+var DefaultReconnectGovernor = NewReconnectGovernor(500*time.Millisecond, 30*time.Second, 5)
+
+func NewReconnectGovernor(baseDelay, maxDelay time.Duration, maxPerSecond float64) *ReconnectGovernor {
+	return &ReconnectGovernor{
+		BaseDelay:    baseDelay,
+		MaxDelay:     maxDelay,
+		MaxPerSecond: maxPerSecond,
+		rand:         rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Wait blocks until the caller may make reconnect attempt number attempt
+// (starting at 0) or ctx is done.
+func (g *ReconnectGovernor) Wait(ctx context.Context, attempt int) error {
+	if !sleepClock(ctx, g.clock(), g.Delay(attempt)) {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// Delay reserves a reconnect slot for attempt and returns how long to wait
+// before using it: the attempt's backoff, or longer if the next slot is
+// further away. Slots are handed out from the current time, not from after
+// the backoff, so one connection's long backoff does not hold back others.
+func (g *ReconnectGovernor) Delay(attempt int) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.clock().Now()
+	delay := g.backoff(attempt)
+
+	if g.MaxPerSecond > 0 {
+		slot := now
+		if slot.Before(g.nextSlot) {
+			slot = g.nextSlot
+		}
+		g.nextSlot = slot.Add(time.Duration(float64(time.Second) / g.MaxPerSecond))
+
+		if wait := slot.Sub(now); wait > delay {
+			delay = wait
+		}
+	}
+
+	return delay
+}
+
+func (g *ReconnectGovernor) clock() Clock {
+	if g.Clock == nil {
+		return SystemClock
+	}
+	return g.Clock
+}
+
+func (g *ReconnectGovernor) backoff(attempt int) time.Duration {
+	if g.BaseDelay <= 0 {
+		return 0
+	}
+
+	ceiling := g.BaseDelay
+	for i := 0; i < attempt && (g.MaxDelay <= 0 || ceiling < g.MaxDelay); i++ {
+		ceiling *= 2
+	}
+	if g.MaxDelay > 0 && ceiling > g.MaxDelay {
+		ceiling = g.MaxDelay
+	}
+
+	if g.rand == nil {
+		g.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return time.Duration(g.rand.Int63n(int64(ceiling) + 1))
+}
@@ -0,0 +1,52 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestReconnectGovernorLongBackoffDoesNotHoldBackOthers(t *testing.T) {
+	g := NewReconnectGovernor(time.Millisecond, time.Hour, 5)
+	g.rand = rand.New(rand.NewSource(1))
+
+	long := g.Delay(30)
+	if long < time.Minute {
+		t.Fatalf("attempt 30 delay = %v, want a long backoff for this seed", long)
+	}
+
+	// A fresh connection only waits for its own backoff or the next rate
+	// slot, 200ms away, not for the other connection's backoff.
+	if short := g.Delay(0); short > 250*time.Millisecond {
+		t.Fatalf("attempt 0 delay = %v after a long backoff, want at most one slot", short)
+	}
+}
+
+func TestReconnectGovernorSpacesSlots(t *testing.T) {
+	g := NewReconnectGovernor(0, 0, 10)
+
+	var last time.Duration
+	for i := 0; i < 5; i++ {
+		d := g.Delay(0)
+		if i > 0 && d-last < 90*time.Millisecond {
+			t.Fatalf("delay %d = %v, previous %v: want slots 100ms apart", i, d, last)
+		}
+		last = d
+	}
+}
@@ -0,0 +1,110 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HostResolver dials hosts through static overrides or a custom resolver, for
+// endpoints pinned through private links or split-horizon DNS. TLS still
+// verifies the original host name.
+type HostResolver struct {
+	// Static maps a host name to the addresses to dial in its place, tried
+	// in order.
+	Static map[string][]string
+
+	// Resolver looks up hosts without a static entry. Defaults to
+	// net.DefaultResolver.
+	Resolver *net.Resolver
+
+	// Dialer makes the connections. Defaults to a net.Dialer with a 30
+	// second timeout.
+	Dialer *net.Dialer
+}
+
+// DialContext dials addr, resolving its host through the overrides. Its
+// signature matches http.Transport.DialContext and websocket dialers'
+// NetDialContext.
+func (r *HostResolver) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	ips, err := r.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := r.Dialer
+	if dialer == nil {
+		dialer = &net.Dialer{Timeout: 30 * time.Second}
+	}
+
+	var errs []error
+	for _, ip := range ips {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, fmt.Errorf("dialing %s: %w", host, errors.Join(errs...))
+}
+
+func (r *HostResolver) lookup(ctx context.Context, host string) ([]string, error) {
+	if ips, ok := r.Static[strings.ToLower(host)]; ok && len(ips) > 0 {
+		return ips, nil
+	}
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+
+	resolver := r.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	ips, err := resolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no addresses found for %s", host)
+	}
+	return ips, nil
+}
+
+// NewResolvingTransport returns a clone of base, or of http.DefaultTransport
+// when base is nil, that dials through resolver.
+func NewResolvingTransport(base *http.Transport, resolver *HostResolver) *http.Transport {
+	if base == nil {
+		base = http.DefaultTransport.(*http.Transport)
+	}
+	t := base.Clone()
+	t.DialContext = resolver.DialContext
+	return t
+}
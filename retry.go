@@ -0,0 +1,218 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy retries calls that fail with a transport error, a 429, or a
+// 5xx, or with one of RetryableStatusCodes when set. Mutating calls are only
+// retried on 429, which the API returns before processing the request.
+type RetryPolicy struct {
+	// MaxAttempts includes the first attempt. Values below 2 disable retries.
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first retry, doubling for each retry
+	// after it. Defaults to 100 milliseconds.
+	BaseDelay time.Duration
+
+	// MaxDelay caps the computed delay. Defaults to 10 seconds.
+	MaxDelay time.Duration
+
+	// MaxRetryAfter caps how long a server's Retry-After is honored. When the
+	// server asks for a longer wait the call fails instead. Defaults to one
+	// minute.
+	MaxRetryAfter time.Duration
+
+	// Jitter randomizes each computed delay down by up to this fraction, in
+	// [0, 1], so clients that failed together do not retry together. A
+	// server's Retry-After is never shortened.
+	Jitter float64
+
+	// RetryableStatusCodes replaces the default of 429 and every 5xx.
+	RetryableStatusCodes []int
+}
+
+// Delay returns the wait before retry number attempt, counting from 1. A
+// server's retryAfter, when positive, is used as the minimum.
+func (p *RetryPolicy) Delay(attempt int, retryAfter time.Duration) time.Duration {
+	base := p.BaseDelay
+	if base <= 0 {
+		base = 100 * time.Millisecond
+	}
+	max := p.MaxDelay
+	if max <= 0 {
+		max = 10 * time.Second
+	}
+
+	delay := max
+	if attempt < 32 {
+		if d := base << (attempt - 1); d > 0 && d < max {
+			delay = d
+		}
+	}
+	if jitter := math.Min(p.Jitter, 1); jitter > 0 {
+		delay -= time.Duration(rand.Float64() * jitter * float64(delay))
+	}
+
+	if retryAfter > delay {
+		return retryAfter
+	}
+	return delay
+}
+
+func (p *RetryPolicy) maxRetryAfter() time.Duration {
+	if p.MaxRetryAfter <= 0 {
+		return time.Minute
+	}
+	return p.MaxRetryAfter
+}
+
+// retrying wraps next so that failed calls are retried according to policy.
+func retrying(policy *RetryPolicy, next CallFunc) CallFunc {
+	if policy == nil || policy.MaxAttempts < 2 {
+		return next
+	}
+
+	return func(ctx context.Context, request *ApiRequest, headersFunc HeaderFunc) *ApiResponse {
+		config := configOf(request.Client)
+		start := config.now()
+		var codes []int
+
+		for attempt := 1; ; attempt++ {
+			response := next(ctx, request, headersFunc)
+			codes = append(codes, response.statusCode)
+
+			delay, retry := policy.next(ctx, request, response, attempt, config.now())
+			if !retry {
+				response.recordAttempts(codes, config.now().Sub(start))
+				return response
+			}
+			recordRetry(ctx, configOf(request.Client), request, response.statusCode, attempt)
+			response.release()
+
+			if !sleepClock(ctx, config.clock(), delay) {
+				err := classifyError(ctx, ctx.Err())
+				aborted := NewApiErrorResponse(request, &ApiError{
+					Message:   err.Error(),
+					ParsedUrl: request.url(),
+					Err:       err,
+				})
+				aborted.recordAttempts(codes, config.now().Sub(start))
+				return aborted
+			}
+		}
+	}
+}
+
+// next decides whether response should be retried, and after how long.
+func (p *RetryPolicy) next(ctx context.Context, request *ApiRequest, response *ApiResponse, attempt int, now time.Time) (time.Duration, bool) {
+	if response.err == nil || attempt >= p.MaxAttempts || ctx.Err() != nil {
+		return 0, false
+	}
+	if !p.isRetryable(request, response) {
+		return 0, false
+	}
+
+	retryAfter, _ := ParseRetryAfter(response.header.Get("Retry-After"), now)
+	if retryAfter > p.maxRetryAfter() {
+		return 0, false
+	}
+
+	delay := p.Delay(attempt, retryAfter)
+	if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+		return 0, false
+	}
+	return delay, true
+}
+
+func (p *RetryPolicy) isRetryable(request *ApiRequest, response *ApiResponse) bool {
+	code := response.statusCode
+	if code == 0 {
+		return isIdempotentMethod(request.HttpMethod) && CancelReasonOf(response.err) == CancelReasonNone &&
+			!errors.Is(response.err, ErrRateLimited) && !isLocalError(response.err)
+	}
+	if !p.isRetryableStatusCode(code) {
+		return false
+	}
+	return code == http.StatusTooManyRequests || isIdempotentMethod(request.HttpMethod)
+}
+
+func (p *RetryPolicy) isRetryableStatusCode(code int) bool {
+	if len(p.RetryableStatusCodes) == 0 {
+		return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+	}
+	for _, c := range p.RetryableStatusCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+func isIdempotentMethod(httpMethod string) bool {
+	switch httpMethod {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// recordAttempts replaces the single-attempt history set by makeCall with
+// the history across retries.
+func (r *ApiResponse) recordAttempts(codes []int, duration time.Duration) {
+	r.attemptStatusCodes = codes
+	r.duration = duration
+	if r.err != nil {
+		r.err.Attempts = len(codes)
+		r.err.AttemptStatusCodes = append([]int(nil), codes...)
+		r.err.Duration = duration
+	}
+}
+
+// ParseRetryAfter parses a Retry-After value, either delay seconds or an HTTP
+// date, into the wait from now.
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := at.Sub(now); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
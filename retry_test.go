@@ -0,0 +1,47 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestRetryPolicySkipsLocalErrors(t *testing.T) {
+	policy := &RetryPolicy{MaxAttempts: 3}
+	request := &ApiRequest{HttpMethod: http.MethodGet}
+
+	for _, err := range []error{
+		&SigningError{Err: errors.New("no key")},
+		&SigningError{Err: ErrSignedRequestModified},
+		fmt.Errorf("%w: signed 2s ago", ErrSignatureExpired),
+		fmt.Errorf("%w: %w", ErrInvalidUrl, errors.New("bad port")),
+		ErrCircuitOpen,
+	} {
+		response := NewApiErrorResponse(request, &ApiError{Message: err.Error(), Err: err})
+		if policy.isRetryable(request, response) {
+			t.Errorf("%v is retried", err)
+		}
+	}
+
+	transport := NewApiErrorResponse(request, &ApiError{Message: "connection reset", Err: errors.New("connection reset")})
+	if !policy.isRetryable(request, transport) {
+		t.Error("transport error is not retried")
+	}
+}
@@ -0,0 +1,163 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coinbase-samples/core-go"
+	"github.com/coinbase-samples/core-go/testutil"
+)
+
+// runScriptedFeed is a minimal feed loop: dial, subscribe, read until the
+// connection fails, then wait for the governor and dial again.
+func runScriptedFeed(ctx context.Context, s *testutil.Scenario, g *core.ReconnectGovernor, subscribe core.SubscribeMessage, received chan<- string) {
+	for attempt := 0; ; attempt++ {
+		conn, err := s.Dial(ctx)
+		if err != nil {
+			return
+		}
+		stop := context.AfterFunc(ctx, func() { conn.Close() })
+
+		if data, err := core.EncodeSubscribeMessage(ctx, subscribe); err == nil {
+			conn.WriteMessage(data)
+		}
+		for {
+			message, err := conn.ReadMessage()
+			if err != nil {
+				break
+			}
+			received <- string(message)
+		}
+		stop()
+
+		if g.Wait(ctx, attempt) != nil {
+			return
+		}
+	}
+}
+
+func TestScenarioDrivesReconnectAndResubscribe(t *testing.T) {
+	subscribe := `{"type":"subscribe","channel":"ticker","product_ids":["BTC-USD"]}`
+	s := testutil.NewScenario(t).
+		ExpectWrite(subscribe).
+		Send(`{"seq":1}`, `{"seq":2}`).
+		Drop(1006).
+		Reconnect().
+		ExpectWrite(subscribe).
+		Send(`{"seq":3}`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	received := make(chan string, 8)
+	go runScriptedFeed(ctx, s, core.NewReconnectGovernor(0, 0, 0), core.SubscribeMessage{
+		"type":        "subscribe",
+		"channel":     "ticker",
+		"product_ids": []string{"BTC-USD"},
+	}, received)
+
+	if err := s.Wait(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`{"seq":1}`, `{"seq":2}`, `{"seq":3}`} {
+		if got := <-received; got != want {
+			t.Fatalf("received %s, want %s", got, want)
+		}
+	}
+	if dials := s.Dials(); dials != 2 {
+		t.Fatalf("dialed %d times, want 2", dials)
+	}
+}
+
+// signedWrite matches a payload equal to want once its "token" field, which
+// must be tokenWant, is removed.
+func signedWrite(want string, tokenWant float64) func(data []byte) error {
+	return func(data []byte) error {
+		var got, expected map[string]interface{}
+		if err := json.Unmarshal(data, &got); err != nil {
+			return err
+		}
+		json.Unmarshal([]byte(want), &expected)
+		if got["token"] != tokenWant {
+			return fmt.Errorf("wrote %s, want token %v", data, tokenWant)
+		}
+		delete(got, "token")
+		if !reflect.DeepEqual(got, expected) {
+			return fmt.Errorf("wrote %s, want %s plus a token", data, want)
+		}
+		return nil
+	}
+}
+
+func TestSubscriptionManagerReplaysSignedSubscriptionsAfterReconnect(t *testing.T) {
+	level2 := `{"type":"subscribe","channel":"level2","product_ids":["BTC-USD","ETH-USD"]}`
+	user := `{"type":"subscribe","channel":"user","product_ids":["BTC-USD"]}`
+	unsubscribe := `{"type":"unsubscribe","channel":"level2","product_ids":["ETH-USD"]}`
+	s := testutil.NewScenario(t).
+		ExpectWriteFunc(level2, signedWrite(level2, 1)).
+		ExpectWriteFunc(user, signedWrite(user, 2)).
+		ExpectWriteFunc(unsubscribe, signedWrite(unsubscribe, 3)).
+		Drop(1006).
+		Reconnect().
+		ExpectWriteFunc("level2 for BTC-USD", signedWrite(`{"type":"subscribe","channel":"level2","product_ids":["BTC-USD"]}`, 4)).
+		ExpectWriteFunc(user, signedWrite(user, 5))
+
+	ctx := context.Background()
+	conn, err := s.Dial(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tokens atomic.Int32
+	m := &core.SubscriptionManager{
+		Send: func(ctx context.Context, payload []byte) error { return conn.WriteMessage(payload) },
+		Decorators: []core.AuthDecorator{core.AuthDecoratorFunc(func(ctx context.Context, message core.SubscribeMessage) error {
+			message["token"] = tokens.Add(1)
+			return nil
+		})},
+	}
+	for _, message := range []core.SubscribeMessage{
+		{"type": "subscribe", "channel": "level2", "product_ids": []string{"BTC-USD", "ETH-USD"}},
+		{"type": "subscribe", "channel": "user", "product_ids": []string{"BTC-USD"}},
+	} {
+		if err := m.Subscribe(ctx, message); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Unsubscribe(ctx, core.SubscribeMessage{"type": "unsubscribe", "channel": "level2", "product_ids": []string{"ETH-USD"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := conn.ReadMessage(); err == nil {
+		t.Fatal("read succeeded, want the scripted drop")
+	}
+	if conn, err = s.Dial(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Resubscribe(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Wait(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
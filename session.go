@@ -0,0 +1,120 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// SessionCall performs a single keep-alive or extend-session request.
+type SessionCall func(ctx context.Context) error
+
+// SessionKeeper invokes Call every Interval until its context is done. Calls
+// made through this package's HttpPost/HttpGet helpers get the same client
+// behavior as any other request.
+type SessionKeeper struct {
+	Interval time.Duration
+	Call     SessionCall
+
+	// Timeout bounds each call. Zero means the call only ends with the
+	// keeper's context.
+	Timeout time.Duration
+
+	// OnFailure, when set, receives every error returned by Call.
+	OnFailure func(err error)
+
+	// Clock schedules the calls. Defaults to SystemClock; a TickerClock
+	// such as testutil.FakeClock makes the keeper deterministic in tests.
+	Clock Clock
+}
+
+func NewSessionKeeper(interval time.Duration, call SessionCall, onFailure func(err error)) *SessionKeeper {
+	return &SessionKeeper{
+		Interval:  interval,
+		Call:      call,
+		OnFailure: onFailure,
+	}
+}
+
+// NewRestSessionKeeper returns a SessionKeeper that posts request to path on
+// client every interval and discards the response body.
+func NewRestSessionKeeper(
+	client Client,
+	path string,
+	request interface{},
+	headersFunc HeaderFunc,
+	interval time.Duration,
+	onFailure func(err error),
+) *SessionKeeper {
+	return NewSessionKeeper(interval, func(ctx context.Context) error {
+		return HttpPost(ctx, client, path, EmptyQueryParams, request, nil, headersFunc)
+	}, onFailure)
+}
+
+// Run blocks, invoking Call on every tick, until ctx is done. It returns the
+// context's error.
+func (k *SessionKeeper) Run(ctx context.Context) error {
+	if k.Call == nil {
+		return errors.New("session keeper requires a call")
+	}
+	if k.Interval <= 0 {
+		return errors.New("session keeper interval must be positive")
+	}
+
+	ticker := newTicker(k.Clock, k.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C():
+			k.invoke(ctx)
+		}
+	}
+}
+
+// Start runs the keeper in a new goroutine and returns a function that stops it.
+func (k *SessionKeeper) Start(ctx context.Context) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		k.Run(ctx)
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func (k *SessionKeeper) invoke(ctx context.Context) {
+	callCtx := ctx
+	if k.Timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, k.Timeout)
+		defer cancel()
+	}
+
+	if err := k.Call(callCtx); err != nil && k.OnFailure != nil && ctx.Err() == nil {
+		k.OnFailure(err)
+	}
+}
@@ -0,0 +1,209 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Signer authenticates a request, typically by setting headers.
+type Signer interface {
+	Sign(req *http.Request, path string, body []byte, t time.Time) error
+}
+
+// SignerRegistration describes a signature algorithm. Detect reports whether
+// credentials are in the algorithm's key format, and New builds a Signer for
+// them.
+type SignerRegistration struct {
+	Name   string
+	Detect func(credentials *Credentials) bool
+	New    func(credentials *Credentials) (Signer, error)
+}
+
+var (
+	signersMu sync.RWMutex
+	signers   []SignerRegistration
+)
+
+func init() {
+	RegisterSigner(SignerRegistration{Name: "hmac-sha256", Detect: isHmacKey, New: newHmacSigner})
+	RegisterSigner(SignerRegistration{Name: "ed25519-jwt", Detect: isEd25519Key, New: newEd25519Signer})
+	RegisterSigner(SignerRegistration{Name: "es256-jwt", Detect: isEcPemKey, New: newEs256Signer})
+}
+
+// RegisterSigner adds an algorithm. Registrations are tried newest first, so
+// a new key format takes precedence over the built-in ones.
+func RegisterSigner(registration SignerRegistration) {
+	signersMu.Lock()
+	defer signersMu.Unlock()
+	signers = append([]SignerRegistration{registration}, signers...)
+}
+
+// NewSigner returns a Signer for the first registered algorithm whose key
+// format matches credentials.
+func NewSigner(credentials *Credentials) (Signer, error) {
+	if credentials == nil {
+		return nil, errors.New("credentials are required to create a signer")
+	}
+
+	signersMu.RLock()
+	defer signersMu.RUnlock()
+
+	for _, registration := range signers {
+		if registration.Detect(credentials) {
+			return registration.New(credentials)
+		}
+	}
+	return nil, fmt.Errorf("no registered signer supports the signing key: %s", credentials.KeyFormat())
+}
+
+// DeadlineSigner is optionally implemented by a Signer whose signatures can
+// expire with the call. When the request's context has a deadline, it is
+// signed with SignUntil instead of Sign.
+type DeadlineSigner interface {
+	SignUntil(req *http.Request, path string, body []byte, t, deadline time.Time) error
+}
+
+// SignerHeaderFunc adapts signer to a HeaderFunc. If signing fails the call
+// fails with a *SigningError.
+func SignerHeaderFunc(signer Signer) HeaderFunc {
+	return func(req *http.Request, path string, body []byte, client Client, t time.Time) {
+		if err := signRequest(signer, req, path, body, t); err != nil {
+			FailSigning(req, err)
+		}
+	}
+}
+
+// signRequest signs req, passing its context's deadline to signers that
+// accept one.
+func signRequest(signer Signer, req *http.Request, path string, body []byte, t time.Time) error {
+	if s, ok := signer.(DeadlineSigner); ok {
+		if deadline, ok := req.Context().Deadline(); ok {
+			return s.SignUntil(req, path, body, t, deadline)
+		}
+	}
+	return signer.Sign(req, path, body, t)
+}
+
+// HmacSigner sets the CB-ACCESS-* headers used by Coinbase Exchange and
+// Coinbase International Exchange, signing with HmacSignature. The signing
+// key is the base64 API secret those APIs issue.
+type HmacSigner struct {
+	Credentials *Credentials
+}
+
+func (s *HmacSigner) Sign(req *http.Request, path string, body []byte, t time.Time) error {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+
+	requestPath := path
+	if req.URL.RawQuery != "" {
+		requestPath += "?" + req.URL.RawQuery
+	}
+	signature, err := s.Credentials.HmacSignature(timestamp, req.Method, requestPath, body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("CB-ACCESS-KEY", s.Credentials.AccessKey)
+	req.Header.Set("CB-ACCESS-SIGN", signature)
+	req.Header.Set("CB-ACCESS-TIMESTAMP", timestamp)
+	if s.Credentials.Passphrase != "" {
+		req.Header.Set("CB-ACCESS-PASSPHRASE", s.Credentials.Passphrase)
+	}
+	return nil
+}
+
+// HmacSignatureHeaderFunc returns a HeaderFunc setting the CB-ACCESS-KEY,
+// CB-ACCESS-SIGN, CB-ACCESS-TIMESTAMP, and, when set, CB-ACCESS-PASSPHRASE
+// headers that Coinbase Exchange and Coinbase International Exchange expect.
+func HmacSignatureHeaderFunc(credentials *Credentials) HeaderFunc {
+	return SignerHeaderFunc(&HmacSigner{Credentials: credentials})
+}
+
+// Sign sets a bearer token minted for the request, without caching.
+func (s *JwtSigner) Sign(req *http.Request, path string, body []byte, t time.Time) error {
+	token, err := s.Token(JwtUri(req.Method, req.URL.Host, path), t)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// SignUntil sets a bearer token that expires with the call's deadline, or
+// after the usual ttl if that is sooner.
+func (s *JwtSigner) SignUntil(req *http.Request, path string, body []byte, t, deadline time.Time) error {
+	token, err := s.TokenUntil(JwtUri(req.Method, req.URL.Host, path), t, deadline)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// cachingJwtSigner is not a DeadlineSigner: cached tokens are shared across
+// calls, so they keep the full ttl.
+type cachingJwtSigner struct {
+	signer *JwtSigner
+	cache  *JwtTokenCache
+}
+
+func (s *cachingJwtSigner) Sign(req *http.Request, path string, body []byte, t time.Time) error {
+	token, err := s.cache.Token(s.signer, JwtUri(req.Method, req.URL.Host, path), t)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+func newHmacSigner(credentials *Credentials) (Signer, error) {
+	return &HmacSigner{Credentials: credentials}, nil
+}
+
+func newEs256Signer(credentials *Credentials) (Signer, error) {
+	signer, err := NewJwtSigner(credentials.AccessKey, credentials.SigningKey)
+	if err != nil {
+		return nil, err
+	}
+	return &cachingJwtSigner{signer: signer, cache: DefaultJwtTokenCache}, nil
+}
+
+func newEd25519Signer(credentials *Credentials) (Signer, error) {
+	signer, err := NewEd25519JwtSigner(credentials.AccessKey, credentials.SigningKey)
+	if err != nil {
+		return nil, err
+	}
+	return &cachingJwtSigner{signer: signer, cache: DefaultJwtTokenCache}, nil
+}
+
+func isHmacKey(credentials *Credentials) bool {
+	return credentials.KeyFormat() == KeyFormatHmac
+}
+
+func isEcPemKey(credentials *Credentials) bool {
+	return credentials.KeyFormat() == KeyFormatEcPem
+}
+
+func isEd25519Key(credentials *Credentials) bool {
+	return credentials.KeyFormat() == KeyFormatEd25519
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// testHmacSecret is base64 of the bytes 0 through 63, in the form Coinbase
+// Exchange and International Exchange issue API secrets.
+const testHmacSecret = "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8gISIjJCUmJygpKissLS4vMDEyMzQ1Njc4OTo7PD0+Pw=="
+
+// The expected signatures follow the documented Exchange and International
+// Exchange steps, computed outside Go with Python's hmac module:
+// base64(HMAC-SHA256(base64decode(secret), timestamp + method + requestPath
+// + body)), where requestPath includes the query string.
+func TestHmacSignatureHeaderFuncKnownVectors(t *testing.T) {
+	signedAt := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name        string
+		credentials *Credentials
+		method      string
+		url         string
+		path        string
+		body        []byte
+		want        http.Header
+	}{
+		{
+			name:        "order with passphrase",
+			credentials: &Credentials{AccessKey: "test-access-key", Passphrase: "test-passphrase", SigningKey: testHmacSecret},
+			method:      http.MethodPost,
+			url:         "https://api.exchange.coinbase.com/orders",
+			path:        "/orders",
+			body:        []byte(`{"product_id":"BTC-USD","side":"buy","size":"0.01","price":"50000.00"}`),
+			want: http.Header{
+				"Cb-Access-Key":        {"test-access-key"},
+				"Cb-Access-Sign":       {"1D+QjeQrpcHq3NuFAsBfMA/eoLRXbKk6FpA5HxQhNbg="},
+				"Cb-Access-Timestamp":  {"1700000000"},
+				"Cb-Access-Passphrase": {"test-passphrase"},
+			},
+		},
+		{
+			name:        "query signed, no passphrase",
+			credentials: &Credentials{AccessKey: "test-access-key", SigningKey: testHmacSecret},
+			method:      http.MethodGet,
+			url:         "https://api.exchange.coinbase.com/orders?status=open&limit=10",
+			path:        "/orders",
+			want: http.Header{
+				"Cb-Access-Key":       {"test-access-key"},
+				"Cb-Access-Sign":      {"/lqiYB4t8RrBk3NwLeVFSlgJ3+z+XkS9ZR9ySW9h+Ag="},
+				"Cb-Access-Timestamp": {"1700000000"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.url, bytes.NewReader(tt.body))
+			ctx, signing := withSigningFailure(req.Context())
+			req = req.WithContext(ctx)
+			HmacSignatureHeaderFunc(tt.credentials)(req, tt.path, tt.body, nil, signedAt)
+			if signing.err != nil {
+				t.Fatal(signing.err)
+			}
+
+			for name := range req.Header {
+				if _, ok := tt.want[name]; !ok {
+					t.Errorf("unexpected header %s: %q", name, req.Header.Get(name))
+				}
+			}
+			for name, want := range tt.want {
+				if got := req.Header.Values(name); len(got) != 1 || got[0] != want[0] {
+					t.Errorf("%s = %q, want %q", name, got, want[0])
+				}
+			}
+		})
+	}
+}
+
+func TestHmacSignerRejectsSecretThatIsNotBase64(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://api.exchange.coinbase.com/accounts", nil)
+	signer := &HmacSigner{Credentials: &Credentials{AccessKey: "test-access-key", SigningKey: "not base64!"}}
+
+	var corrupt base64.CorruptInputError
+	if err := signer.Sign(req, "/accounts", nil, time.Unix(1700000000, 0)); !errors.As(err, &corrupt) {
+		t.Fatalf("Sign = %v, want a base64 decoding error", err)
+	}
+}
@@ -0,0 +1,197 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// BurnRateThreshold fires when the error budget burns faster than Rate times
+// the sustainable pace over Window. For example, with a 99.9% objective, a
+// Rate of 14.4 over one hour means 2% of a 30 day budget spent in that hour.
+type BurnRateThreshold struct {
+	Window time.Duration
+	Rate   float64
+}
+
+// SloAlert describes a threshold that was crossed.
+type SloAlert struct {
+	Threshold  BurnRateThreshold
+	BurnRate   float64
+	ErrorRatio float64
+	Total      uint64
+	Failed     uint64
+}
+
+type sloBucket struct {
+	start  int64
+	total  uint64
+	failed uint64
+}
+
+// SloTracker is a MetricsRecorder that tracks the success ratio of calls over
+// sliding windows and calls OnAlert when a burn-rate threshold is crossed. An
+// alert fires once per crossing and re-arms after the burn rate recovers.
+type SloTracker struct {
+	objective  float64
+	thresholds []BurnRateThreshold
+	resolution time.Duration
+
+	// OnAlert receives crossed thresholds. It is called without locks held.
+	OnAlert func(alert SloAlert)
+
+	// IsFailure classifies a call. Defaults to transport errors, 429s, and 5xxs.
+	IsFailure func(statusCode int) bool
+
+	// MinCalls is the number of calls a window must hold before it can alert,
+	// so a single early failure does not page. Defaults to 10.
+	MinCalls uint64
+
+	mu        sync.Mutex
+	buckets   []sloBucket
+	firing    []bool
+	evaluated int64
+}
+
+// NewSloTracker returns a tracker for objective, the target success ratio such
+// as 0.999, evaluating each of thresholds.
+func NewSloTracker(objective float64, thresholds ...BurnRateThreshold) *SloTracker {
+	var longest time.Duration
+	for _, t := range thresholds {
+		if t.Window > longest {
+			longest = t.Window
+		}
+	}
+
+	resolution := longest / 120
+	if resolution < time.Second {
+		resolution = time.Second
+	}
+
+	size := int(longest/resolution) + 1
+	return &SloTracker{
+		objective:  objective,
+		thresholds: append([]BurnRateThreshold(nil), thresholds...),
+		resolution: resolution,
+		buckets:    make([]sloBucket, size),
+		firing:     make([]bool, len(thresholds)),
+		MinCalls:   10,
+	}
+}
+
+func (s *SloTracker) RecordRequest(httpMethod, path string, statusCode int, duration time.Duration) {
+	s.record(time.Now(), s.isFailure(statusCode))
+}
+
+// Record adds an outcome directly, for calls not made through this package.
+func (s *SloTracker) Record(success bool) {
+	s.record(time.Now(), !success)
+}
+
+// BurnRate returns the current burn rate over window.
+func (s *SloTracker) BurnRate(window time.Duration) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	total, failed := s.sum(time.Now(), window)
+	return s.burnRate(total, failed)
+}
+
+func (s *SloTracker) record(now time.Time, failed bool) {
+	s.mu.Lock()
+
+	slot := now.UnixNano() / int64(s.resolution)
+	b := &s.buckets[slot%int64(len(s.buckets))]
+	if b.start != slot {
+		*b = sloBucket{start: slot}
+	}
+	b.total++
+	if failed {
+		b.failed++
+	}
+
+	var alerts []SloAlert
+	if slot != s.evaluated {
+		s.evaluated = slot
+		alerts = s.evaluate(now)
+	}
+	s.mu.Unlock()
+
+	if s.OnAlert != nil {
+		for _, alert := range alerts {
+			s.OnAlert(alert)
+		}
+	}
+}
+
+func (s *SloTracker) evaluate(now time.Time) []SloAlert {
+	var alerts []SloAlert
+	for i, t := range s.thresholds {
+		total, failed := s.sum(now, t.Window)
+		rate := s.burnRate(total, failed)
+
+		if total < s.MinCalls {
+			continue
+		}
+		if rate < t.Rate {
+			s.firing[i] = false
+			continue
+		}
+		if s.firing[i] {
+			continue
+		}
+		s.firing[i] = true
+
+		alerts = append(alerts, SloAlert{
+			Threshold:  t,
+			BurnRate:   rate,
+			ErrorRatio: float64(failed) / float64(total),
+			Total:      total,
+			Failed:     failed,
+		})
+	}
+	return alerts
+}
+
+func (s *SloTracker) sum(now time.Time, window time.Duration) (total, failed uint64) {
+	current := now.UnixNano() / int64(s.resolution)
+	oldest := current - int64(window/s.resolution)
+	for _, b := range s.buckets {
+		if b.start > oldest && b.start <= current {
+			total += b.total
+			failed += b.failed
+		}
+	}
+	return total, failed
+}
+
+func (s *SloTracker) burnRate(total, failed uint64) float64 {
+	budget := 1 - s.objective
+	if total == 0 || budget <= 0 {
+		return 0
+	}
+	return float64(failed) / float64(total) / budget
+}
+
+func (s *SloTracker) isFailure(statusCode int) bool {
+	if s.IsFailure != nil {
+		return s.IsFailure(statusCode)
+	}
+	return statusCode == 0 || statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
@@ -0,0 +1,225 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Snapshot is serialized state, such as an order book, together with the
+// last feed sequence applied to it, so a restarted consumer can resume from
+// the snapshot and only replay what came after.
+type Snapshot struct {
+	// Key identifies the state, e.g. a product id.
+	Key      string    `json:"key"`
+	Sequence int64     `json:"sequence"`
+	Data     []byte    `json:"data"`
+	TakenAt  time.Time `json:"taken_at"`
+}
+
+// SnapshotStore persists the latest snapshot per key. Load returns nil when
+// there is none.
+type SnapshotStore interface {
+	Save(ctx context.Context, snapshot *Snapshot) error
+	Load(ctx context.Context, key string) (*Snapshot, error)
+}
+
+// Snapshotter periodically captures state and saves it to a SnapshotStore.
+// A snapshot whose sequence has not moved since it was last saved is not
+// written again.
+type Snapshotter struct {
+	Store SnapshotStore
+
+	// Interval is the time between captures.
+	Interval time.Duration
+
+	// Capture returns the current snapshots, one per key.
+	Capture func(ctx context.Context) ([]*Snapshot, error)
+
+	// OnError, when set, receives capture and save failures from Run.
+	OnError func(err error)
+
+	// Clock schedules Run and stamps snapshots without a TakenAt. Defaults
+	// to SystemClock.
+	Clock Clock
+
+	mu    sync.Mutex
+	saved map[string]int64
+}
+
+// Run captures and saves every Interval until ctx is done, and returns the
+// context's error. Call Flush afterwards to save the final state.
+func (s *Snapshotter) Run(ctx context.Context) error {
+	if s.Interval <= 0 {
+		return errors.New("snapshotter interval must be positive")
+	}
+
+	ticker := newTicker(s.Clock, s.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C():
+			if err := s.Flush(ctx); err != nil && s.OnError != nil && ctx.Err() == nil {
+				s.OnError(err)
+			}
+		}
+	}
+}
+
+// Flush captures and saves snapshots once. Every snapshot is attempted, and
+// the errors are joined.
+func (s *Snapshotter) Flush(ctx context.Context) error {
+	snapshots, err := s.Capture(ctx)
+	if err != nil {
+		return fmt.Errorf("capturing snapshots: %w", err)
+	}
+
+	var errs []error
+	for _, snapshot := range snapshots {
+		if !s.changed(snapshot) {
+			continue
+		}
+		if snapshot.TakenAt.IsZero() {
+			snapshot.TakenAt = s.clock().Now()
+		}
+		if err := s.Store.Save(ctx, snapshot); err != nil {
+			errs = append(errs, fmt.Errorf("saving snapshot %s: %w", snapshot.Key, err))
+			continue
+		}
+		s.mu.Lock()
+		s.saved[snapshot.Key] = snapshot.Sequence
+		s.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Snapshotter) changed(snapshot *Snapshot) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.saved == nil {
+		s.saved = map[string]int64{}
+	}
+	sequence, ok := s.saved[snapshot.Key]
+	return !ok || sequence != snapshot.Sequence
+}
+
+func (s *Snapshotter) clock() Clock {
+	if s.Clock == nil {
+		return SystemClock
+	}
+	return s.Clock
+}
+
+// MemorySnapshotStore keeps snapshots in memory. It does not survive a
+// restart and suits tests.
+type MemorySnapshotStore struct {
+	mu        sync.Mutex
+	snapshots map[string]*Snapshot
+}
+
+func NewMemorySnapshotStore() *MemorySnapshotStore {
+	return &MemorySnapshotStore{snapshots: map[string]*Snapshot{}}
+}
+
+func (s *MemorySnapshotStore) Save(ctx context.Context, snapshot *Snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copied := *snapshot
+	s.snapshots[snapshot.Key] = &copied
+	return nil
+}
+
+func (s *MemorySnapshotStore) Load(ctx context.Context, key string) (*Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot, ok := s.snapshots[key]
+	if !ok {
+		return nil, nil
+	}
+	copied := *snapshot
+	return &copied, nil
+}
+
+// FileSnapshotStore keeps each key's snapshot as a JSON file in a directory,
+// written atomically, so a crash mid-save leaves the previous snapshot.
+type FileSnapshotStore struct {
+	dir string
+}
+
+// NewFileSnapshotStore returns a store in dir, creating it when needed.
+func NewFileSnapshotStore(dir string) (*FileSnapshotStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileSnapshotStore{dir: dir}, nil
+}
+
+func (s *FileSnapshotStore) Save(ctx context.Context, snapshot *Snapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".snapshot-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(snapshot.Key))
+}
+
+func (s *FileSnapshotStore) Load(ctx context.Context, key string) (*Snapshot, error) {
+	data, err := os.ReadFile(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &Snapshot{}
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		return nil, fmt.Errorf("parsing snapshot %s: %w", key, err)
+	}
+	return snapshot, nil
+}
+
+func (s *FileSnapshotStore) path(key string) string {
+	return filepath.Join(s.dir, url.PathEscape(key)+".json")
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coinbase-samples/core-go"
+	"github.com/coinbase-samples/core-go/testutil"
+)
+
+// countingSnapshotStore counts the saves reaching the store it wraps.
+type countingSnapshotStore struct {
+	core.SnapshotStore
+	saves atomic.Int32
+}
+
+func (s *countingSnapshotStore) Save(ctx context.Context, snapshot *core.Snapshot) error {
+	s.saves.Add(1)
+	return s.SnapshotStore.Save(ctx, snapshot)
+}
+
+func TestSnapshotterSavesPeriodicallyAndRestores(t *testing.T) {
+	dir := t.TempDir()
+	files, err := core.NewFileSnapshotStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &countingSnapshotStore{SnapshotStore: files}
+	clock := testutil.NewFakeClock(time.Time{})
+
+	var sequence atomic.Int64
+	var captures atomic.Int32
+	sequence.Store(41)
+	s := &core.Snapshotter{
+		Store:    store,
+		Interval: time.Second,
+		Clock:    clock,
+		Capture: func(ctx context.Context) ([]*core.Snapshot, error) {
+			defer captures.Add(1)
+			return []*core.Snapshot{{Key: "BTC-USD", Sequence: sequence.Load(), Data: []byte(`{"bids":[],"asks":[]}`)}}, nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+
+	clock.BlockUntil(1)
+	for i := int32(1); i <= 2; i++ {
+		clock.Advance(time.Second)
+		waitFor(t, "a capture", func() bool { return captures.Load() == i })
+	}
+	if n := store.saves.Load(); n != 1 {
+		t.Fatalf("%d saves for an unchanged sequence, want 1", n)
+	}
+	sequence.Store(42)
+	clock.Advance(time.Second)
+	waitFor(t, "the advanced snapshot to be saved", func() bool { return store.saves.Load() == 2 })
+	cancel()
+	<-done
+
+	restarted, err := core.NewFileSnapshotStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := restarted.Load(context.Background(), "BTC-USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot == nil || snapshot.Sequence != 42 || string(snapshot.Data) != `{"bids":[],"asks":[]}` || snapshot.TakenAt.IsZero() {
+		t.Fatalf("restored snapshot = %+v, want sequence 42 with its data and time", snapshot)
+	}
+	if missing, err := restarted.Load(context.Background(), "ETH-USD"); err != nil || missing != nil {
+		t.Fatalf("Load of an unknown key = %+v, %v; want nil, nil", missing, err)
+	}
+}
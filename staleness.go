@@ -0,0 +1,264 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// StalenessEvent reports that a channel/product went quiet for longer than its
+// SLA, or, with Recovered set, that messages resumed afterwards.
+type StalenessEvent struct {
+	Channel   string
+	ProductId string
+	LastSeen  time.Time
+	Gap       time.Duration
+	Sla       time.Duration
+	Recovered bool
+}
+
+type feedKey struct {
+	channel   string
+	productId string
+}
+
+type feedState struct {
+	lastSeen time.Time
+	stale    bool
+
+	// expected marks a channel-level feed started by Watch or Expect rather
+	// than by a message; the first message for any of its products replaces
+	// it.
+	expected bool
+
+	// recovery is the recovery not yet returned by Check. Keeping at most
+	// one per feed bounds the backlog when Check is never called.
+	recovery *StalenessEvent
+}
+
+// StalenessDetector tracks the last message seen per channel and product and
+// reports gaps exceeding the channel's configured SLA. Channels without an SLA
+// are not tracked. A feed's clock starts at its first message, at Expect, or,
+// for channels with no messages yet, when Watch starts, so a subscription
+// that never delivers anything is reported too. It is safe for concurrent use.
+type StalenessDetector struct {
+	// Clock stamps Observe and times Watch. Defaults to SystemClock; a
+	// TickerClock such as testutil.FakeClock makes Watch deterministic in
+	// tests.
+	Clock Clock
+
+	slas map[string]time.Duration
+
+	mu    sync.Mutex
+	feeds map[feedKey]*feedState
+}
+
+// NewStalenessDetector returns a detector using slas, keyed by channel name,
+// as the maximum expected gap between messages.
+func NewStalenessDetector(slas map[string]time.Duration) *StalenessDetector {
+	copied := make(map[string]time.Duration, len(slas))
+	for channel, sla := range slas {
+		copied[channel] = sla
+	}
+	return &StalenessDetector{
+		slas:  copied,
+		feeds: make(map[feedKey]*feedState),
+	}
+}
+
+// Observe records a message on channel for productId (empty for channels that
+// are not per product).
+func (d *StalenessDetector) Observe(channel, productId string) {
+	d.ObserveAt(channel, productId, d.clock().Now())
+}
+
+func (d *StalenessDetector) ObserveAt(channel, productId string, t time.Time) {
+	sla, ok := d.slas[channel]
+	if !ok {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := feedKey{channel: channel, productId: productId}
+	state, tracked := d.feeds[key]
+	if !tracked {
+		state = &feedState{lastSeen: t}
+		d.feeds[key] = state
+	}
+
+	// A channel expected as a whole is replaced by its first product feed,
+	// which carries the channel's recovery if it had gone stale.
+	channelKey := feedKey{channel: channel}
+	if expected, ok := d.feeds[channelKey]; ok && expected.expected && productId != "" {
+		expected.observe(channelKey, t, sla)
+		if expected.recovery != nil {
+			state.recovery = expected.recovery
+		}
+		delete(d.feeds, channelKey)
+	}
+
+	if tracked {
+		state.observe(key, t, sla)
+	}
+}
+
+// observe records a message at t, noting a recovery if the feed was stale.
+func (s *feedState) observe(key feedKey, t time.Time, sla time.Duration) {
+	if s.stale {
+		s.recovery = &StalenessEvent{
+			Channel:   key.channel,
+			ProductId: key.productId,
+			LastSeen:  s.lastSeen,
+			Gap:       t.Sub(s.lastSeen),
+			Sla:       sla,
+			Recovered: true,
+		}
+		s.stale = false
+	}
+	s.lastSeen = t
+	s.expected = false
+}
+
+// Expect starts the clock now for feeds that have not sent anything yet,
+// typically right after subscribing, so they are reported if nothing ever
+// arrives. Without productIds it expects the channel as a whole. Feeds that
+// are already tracked are left alone.
+func (d *StalenessDetector) Expect(channel string, productIds ...string) {
+	d.expectAt(channel, productIds, d.clock().Now())
+}
+
+func (d *StalenessDetector) expectAt(channel string, productIds []string, t time.Time) {
+	if _, ok := d.slas[channel]; !ok {
+		return
+	}
+	if len(productIds) == 0 {
+		productIds = []string{""}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, productId := range productIds {
+		key := feedKey{channel: channel, productId: productId}
+		if _, ok := d.feeds[key]; !ok {
+			d.feeds[key] = &feedState{lastSeen: t, expected: productId == ""}
+		}
+	}
+}
+
+// expectChannels starts the clock at t for every channel with an SLA that
+// has no tracked feed.
+func (d *StalenessDetector) expectChannels(t time.Time) {
+	d.mu.Lock()
+	tracked := make(map[string]bool, len(d.feeds))
+	for key := range d.feeds {
+		tracked[key.channel] = true
+	}
+	d.mu.Unlock()
+
+	for channel := range d.slas {
+		if !tracked[channel] {
+			d.expectAt(channel, nil, t)
+		}
+	}
+}
+
+// Forget stops tracking channel/productId, e.g. after unsubscribing.
+func (d *StalenessDetector) Forget(channel, productId string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.feeds, feedKey{channel: channel, productId: productId})
+}
+
+// Check returns the events that occurred as of now: one event when a feed
+// first exceeds its SLA and one when it recovers.
+func (d *StalenessDetector) Check(now time.Time) []StalenessEvent {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var events []StalenessEvent
+	for key, state := range d.feeds {
+		if state.recovery != nil {
+			events = append(events, *state.recovery)
+			state.recovery = nil
+		}
+
+		sla := d.slas[key.channel]
+		gap := now.Sub(state.lastSeen)
+		if state.stale || gap <= sla {
+			continue
+		}
+		state.stale = true
+		events = append(events, StalenessEvent{
+			Channel:   key.channel,
+			ProductId: key.productId,
+			LastSeen:  state.lastSeen,
+			Gap:       gap,
+			Sla:       sla,
+		})
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].LastSeen.Before(events[j].LastSeen)
+	})
+
+	return events
+}
+
+// Watch checks every interval and delivers events on the returned channel,
+// which is closed once ctx is done. Channels with an SLA that have seen no
+// messages yet are timed from the call to Watch.
+func (d *StalenessDetector) Watch(ctx context.Context, interval time.Duration) <-chan StalenessEvent {
+	d.expectChannels(d.clock().Now())
+	events := make(chan StalenessEvent, 16)
+
+	go func() {
+		defer close(events)
+
+		ticker := newTicker(d.Clock, interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C():
+				for _, event := range d.Check(now) {
+					select {
+					case events <- event:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	return events
+}
+
+func (d *StalenessDetector) clock() Clock {
+	if d.Clock == nil {
+		return SystemClock
+	}
+	return d.Clock
+}
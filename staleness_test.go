@@ -0,0 +1,73 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStalenessDetectorKeepsRecoveriesWithTheirFeed(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := NewStalenessDetector(map[string]time.Duration{"ticker": time.Second})
+	d.ObserveAt("ticker", "BTC-USD", start)
+	d.ObserveAt("ticker", "ETH-USD", start)
+	if events := d.Check(start.Add(2 * time.Second)); len(events) != 2 {
+		t.Fatalf("events = %+v, want both feeds stale", events)
+	}
+
+	for i := 0; i < 10; i++ {
+		d.ObserveAt("ticker", "BTC-USD", start.Add(3*time.Second))
+		d.ObserveAt("ticker", "ETH-USD", start.Add(3*time.Second))
+	}
+	d.Forget("ticker", "ETH-USD")
+
+	events := d.Check(start.Add(3 * time.Second))
+	if len(events) != 1 || !events[0].Recovered || events[0].ProductId != "BTC-USD" {
+		t.Fatalf("events = %+v, want one recovery for BTC-USD", events)
+	}
+	if events := d.Check(start.Add(3 * time.Second)); len(events) != 0 {
+		t.Fatalf("events = %+v after draining, want none", events)
+	}
+}
+
+func TestStalenessDetectorFlagsFeedsThatNeverSend(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := NewStalenessDetector(map[string]time.Duration{"ticker": 5 * time.Second, "level2": 5 * time.Second})
+	d.Clock = fixedClock(start)
+	d.Expect("level2", "BTC-USD")
+	d.expectChannels(start)
+
+	events := d.Check(start.Add(6 * time.Second))
+	if len(events) != 2 {
+		t.Fatalf("events = %+v, want the silent level2 product and ticker channel", events)
+	}
+	for _, event := range events {
+		if event.Recovered || event.Gap != 6*time.Second {
+			t.Fatalf("event = %+v, want stale for 6s since the watch started", event)
+		}
+	}
+
+	d.ObserveAt("ticker", "ETH-USD", start.Add(7*time.Second))
+	events = d.Check(start.Add(8 * time.Second))
+	if len(events) != 1 || !events[0].Recovered || events[0].Channel != "ticker" || events[0].ProductId != "" {
+		t.Fatalf("events = %+v, want the ticker channel to recover", events)
+	}
+	if _, ok := d.feeds[feedKey{channel: "ticker"}]; ok {
+		t.Fatal("the channel placeholder was kept after a product feed replaced it")
+	}
+}
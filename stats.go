@@ -0,0 +1,210 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+)
+
+// ErrNonFiniteSample is returned when a NaN or infinite value is added to an
+// Ema or RollingWindow. Such a value would otherwise poison every later
+// result.
+var ErrNonFiniteSample = errors.New("sample is not a finite number")
+
+// Ema is an exponential moving average. It is safe for concurrent use.
+type Ema struct {
+	mu          sync.Mutex
+	alpha       float64
+	value       float64
+	initialized bool
+}
+
+// NewEma returns an Ema with the given smoothing factor, which must be in (0, 1].
+func NewEma(alpha float64) (*Ema, error) {
+	if !(alpha > 0 && alpha <= 1) {
+		return nil, fmt.Errorf("ema smoothing factor %v is outside (0, 1]", alpha)
+	}
+	return &Ema{alpha: alpha}, nil
+}
+
+// NewEmaWithPeriod returns an Ema whose smoothing factor is derived from an
+// N-period window using the conventional 2/(N+1). period must be at least 1.
+func NewEmaWithPeriod(period int) (*Ema, error) {
+	if period < 1 {
+		return nil, fmt.Errorf("ema period %d is less than 1", period)
+	}
+	return NewEma(2 / float64(period+1))
+}
+
+// Add folds v into the average and returns the updated value. The first
+// observation seeds the average. A non-finite v is rejected with
+// ErrNonFiniteSample and leaves the average unchanged.
+func (e *Ema) Add(v float64) (float64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !isFinite(v) {
+		return e.value, ErrNonFiniteSample
+	}
+
+	if !e.initialized {
+		e.value = v
+		e.initialized = true
+		return e.value, nil
+	}
+
+	e.value = e.alpha*v + (1-e.alpha)*e.value
+	return e.value, nil
+}
+
+func (e *Ema) Value() float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.value
+}
+
+// Ready reports whether at least one value has been added.
+func (e *Ema) Ready() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.initialized
+}
+
+func (e *Ema) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.value = 0
+	e.initialized = false
+}
+
+// RollingWindow keeps the last N values and reports their min, max, and mean.
+// It is safe for concurrent use.
+type RollingWindow struct {
+	mu     sync.Mutex
+	values []float64
+	next   int
+	count  int
+	sum    float64
+}
+
+func NewRollingWindow(size int) *RollingWindow {
+	if size < 1 {
+		size = 1
+	}
+	return &RollingWindow{values: make([]float64, size)}
+}
+
+// Add records v, evicting the oldest value once the window is full. A
+// non-finite v is rejected with ErrNonFiniteSample.
+func (w *RollingWindow) Add(v float64) error {
+	if !isFinite(v) {
+		return ErrNonFiniteSample
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.count == len(w.values) {
+		w.sum -= w.values[w.next]
+	} else {
+		w.count++
+	}
+
+	w.values[w.next] = v
+	w.sum += v
+	w.next = (w.next + 1) % len(w.values)
+
+	// Subtracting evicted values leaves rounding error in the running sum
+	// that grows with every eviction, so it is recomputed each time the
+	// window wraps. This keeps Add amortized O(1).
+	if w.next == 0 {
+		w.sum = 0
+		for _, value := range w.values {
+			w.sum += value
+		}
+	}
+	return nil
+}
+
+func (w *RollingWindow) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.count
+}
+
+func (w *RollingWindow) Size() int {
+	return len(w.values)
+}
+
+// Full reports whether the window holds Size values.
+func (w *RollingWindow) Full() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.count == len(w.values)
+}
+
+// Mean returns the average of the values in the window, or zero when empty.
+func (w *RollingWindow) Mean() float64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.count == 0 {
+		return 0
+	}
+	return w.sum / float64(w.count)
+}
+
+// Min returns the smallest value in the window, or zero when empty.
+func (w *RollingWindow) Min() float64 {
+	return w.fold(math.Min)
+}
+
+// Max returns the largest value in the window, or zero when empty.
+func (w *RollingWindow) Max() float64 {
+	return w.fold(math.Max)
+}
+
+func (w *RollingWindow) Reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.next = 0
+	w.count = 0
+	w.sum = 0
+}
+
+func (w *RollingWindow) fold(f func(a, b float64) float64) float64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.count == 0 {
+		return 0
+	}
+
+	result := w.values[0]
+	for i := 1; i < w.count; i++ {
+		result = f(result, w.values[i])
+	}
+	return result
+}
+
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
@@ -0,0 +1,73 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestStatsRejectNonFiniteSamples(t *testing.T) {
+	ema, err := NewEma(0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := NewRollingWindow(4)
+	ema.Add(2)
+	w.Add(2)
+
+	for _, v := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		if value, err := ema.Add(v); !errors.Is(err, ErrNonFiniteSample) || value != 2 {
+			t.Fatalf("ema.Add(%v) = %v, %v; want 2, ErrNonFiniteSample", v, value, err)
+		}
+		if err := w.Add(v); !errors.Is(err, ErrNonFiniteSample) {
+			t.Fatalf("window.Add(%v) = %v, want ErrNonFiniteSample", v, err)
+		}
+	}
+	if w.Len() != 1 || w.Mean() != 2 {
+		t.Fatalf("window holds %d values with mean %v, want the one finite sample", w.Len(), w.Mean())
+	}
+}
+
+func TestNewEmaRejectsOutOfRangeSmoothing(t *testing.T) {
+	for _, alpha := range []float64{0, -0.1, 1.5, math.NaN()} {
+		if _, err := NewEma(alpha); err == nil {
+			t.Fatalf("NewEma(%v) succeeded, want an error", alpha)
+		}
+	}
+	if _, err := NewEma(1); err != nil {
+		t.Fatalf("NewEma(1) = %v, want success", err)
+	}
+	if _, err := NewEmaWithPeriod(0); err == nil {
+		t.Fatal("NewEmaWithPeriod(0) succeeded, want an error")
+	}
+	if _, err := NewTimeSync(nil, 2); err == nil {
+		t.Fatal("NewTimeSync with smoothing 2 succeeded, want an error")
+	}
+}
+
+func TestRollingWindowMeanDoesNotDrift(t *testing.T) {
+	w := NewRollingWindow(3)
+	w.Add(1e16)
+	for i := 0; i < 1000; i++ {
+		w.Add(1)
+	}
+	if mean := w.Mean(); mean != 1 {
+		t.Fatalf("mean = %v after the large value was evicted, want 1", mean)
+	}
+}
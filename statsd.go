@@ -0,0 +1,97 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StatsdRecorder is a MetricsRecorder that emits DogStatsD counters and
+// timings over UDP, tagged with method, path, and status. Send failures are
+// ignored, as is usual for StatsD.
+type StatsdRecorder struct {
+	conn      net.Conn
+	namespace string
+	tags      string
+}
+
+// NewStatsdRecorder dials the agent at address (e.g. "127.0.0.1:8125").
+// namespace prefixes metric names, e.g. "coinbase.", and tags, such as
+// "client:prime", are added to every metric.
+func NewStatsdRecorder(address, namespace string, tags ...string) (*StatsdRecorder, error) {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	sanitized := make([]string, len(tags))
+	for i, tag := range tags {
+		sanitized[i] = sanitizeStatsdTag(tag)
+	}
+
+	return &StatsdRecorder{
+		conn:      conn,
+		namespace: namespace,
+		tags:      strings.Join(sanitized, ","),
+	}, nil
+}
+
+func (s *StatsdRecorder) RecordRequest(httpMethod, path string, statusCode int, duration time.Duration) {
+	tags := "method:" + sanitizeStatsdTag(httpMethod) +
+		",path:" + sanitizeStatsdTag(path) +
+		",status:" + strconv.Itoa(statusCode)
+	if s.tags != "" {
+		tags += "," + s.tags
+	}
+
+	var b strings.Builder
+	b.WriteString(s.namespace)
+	b.WriteString("http.requests:1|c|#")
+	b.WriteString(tags)
+	b.WriteByte('\n')
+	b.WriteString(s.namespace)
+	b.WriteString("http.request.duration:")
+	b.WriteString(strconv.FormatFloat(float64(duration)/float64(time.Millisecond), 'f', 3, 64))
+	b.WriteString("|ms|#")
+	b.WriteString(tags)
+
+	s.conn.Write([]byte(b.String()))
+}
+
+func (s *StatsdRecorder) RecordRetry(httpMethod, path string, statusCode, attempt int) {
+	tags := "method:" + sanitizeStatsdTag(httpMethod) +
+		",path:" + sanitizeStatsdTag(path) +
+		",status:" + strconv.Itoa(statusCode)
+	if s.tags != "" {
+		tags += "," + s.tags
+	}
+
+	s.conn.Write([]byte(s.namespace + "http.retries:1|c|#" + tags))
+}
+
+func (s *StatsdRecorder) Close() error {
+	return s.conn.Close()
+}
+
+var statsdTagReplacer = strings.NewReplacer(",", "_", "|", "_", "#", "_", "\n", "_")
+
+func sanitizeStatsdTag(tag string) string {
+	return statsdTagReplacer.Replace(tag)
+}
@@ -0,0 +1,190 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Stream makes a call and returns the response with its body unread, for
+// bodies too large to buffer. The caller must close the body. Middleware does
+// not run for streamed calls. Unexpected status codes are returned as
+// *ApiError with the body already consumed.
+func Stream(
+	ctx context.Context,
+	client Client,
+	path,
+	query,
+	httpMethod string,
+	expectedHttpStatusCodes []int,
+	request interface{},
+	headersFunc HeaderFunc,
+) (*http.Response, error) {
+
+	body, err := marshalRequest(request)
+	if err != nil {
+		return nil, err
+	}
+
+	apiRequest := &ApiRequest{
+		Path:                    path,
+		Query:                   query,
+		HttpMethod:              httpMethod,
+		Body:                    body,
+		ExpectedHttpStatusCodes: expectedHttpStatusCodes,
+		Client:                  client,
+	}
+
+	config := configOf(client)
+	start := time.Now()
+
+	// The call is tracked until its body is closed.
+	ctx, done := config.Tracker.track(ctx, httpMethod, path)
+	res, sendFinish, apiErr := send(ctx, apiRequest, headersFunc)
+	if apiErr != nil {
+		recordRequest(ctx, config, apiRequest, 0, start)
+		done()
+		return nil, apiErr
+	}
+	recordRequest(ctx, config, apiRequest, res.StatusCode, start)
+	finish := func() {
+		sendFinish()
+		done()
+	}
+
+	if !isExpectedStatusCode(apiRequest, res.StatusCode) {
+		defer finish()
+		defer res.Body.Close()
+
+		errBody, err := io.ReadAll(res.Body)
+		if err != nil {
+			return nil, &ApiError{Message: err.Error(), CodeReceived: res.StatusCode}
+		}
+		return nil, typedError(unexpectedStatusError(apiRequest, res, errBody))
+	}
+
+	res.Body = &finishingBody{ReadCloser: res.Body, finish: finish}
+	return res, nil
+}
+
+// GetStream is Stream for a GET expecting 200.
+func GetStream(ctx context.Context, client Client, path, query string, headersFunc HeaderFunc) (*http.Response, error) {
+	return Stream(ctx, client, path, query, http.MethodGet, []int{http.StatusOK}, nil, headersFunc)
+}
+
+// finishingBody releases call resources, such as a scheduler slot, when the
+// streamed body is closed.
+type finishingBody struct {
+	io.ReadCloser
+	once   sync.Once
+	finish func()
+}
+
+func (b *finishingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.finish)
+	return err
+}
+
+// DecodeStream decodes a JSON array from r one element at a time, passing each
+// to fn, so memory stays bounded regardless of the array's length. When field
+// is empty the body must be an array; otherwise the array is read from that
+// field of a top-level object and other fields are skipped. Returning an
+// error from fn stops decoding.
+func DecodeStream[T any](r io.Reader, field string, fn func(item T) error) error {
+	dec := json.NewDecoder(r)
+
+	if field != "" {
+		if err := expectDelim(dec, '{'); err != nil {
+			return err
+		}
+		if err := seekField(dec, field); err != nil {
+			return err
+		}
+	}
+
+	if err := expectDelim(dec, '['); err != nil {
+		return err
+	}
+
+	for dec.More() {
+		var item T
+		if err := dec.Decode(&item); err != nil {
+			return err
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+
+	return expectDelim(dec, ']')
+}
+
+// GetDecodeStream streams a GET response through DecodeStream.
+func GetDecodeStream[T any](
+	ctx context.Context,
+	client Client,
+	path,
+	query,
+	field string,
+	headersFunc HeaderFunc,
+	fn func(item T) error,
+) error {
+	res, err := GetStream(ctx, client, path, query, headersFunc)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	return DecodeStream(res.Body, field, fn)
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != want {
+		return fmt.Errorf("expected %q in JSON stream, got %v", want, tok)
+	}
+	return nil
+}
+
+// seekField advances dec, positioned inside an object, to the value of field.
+func seekField(dec *json.Decoder, field string) error {
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if key, ok := tok.(string); ok && key == field {
+			return nil
+		}
+
+		var skipped json.RawMessage
+		if err := dec.Decode(&skipped); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("field %q not found in JSON stream", field)
+}
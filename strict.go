@@ -0,0 +1,289 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type strictDecodingKey struct{}
+
+// WithStrictDecoding returns a context whose calls fail with an
+// *UnknownFieldsError when a JSON response has fields the response type does
+// not declare, as Config.StrictDecoding does for every call.
+func WithStrictDecoding(ctx context.Context) context.Context {
+	return context.WithValue(ctx, strictDecodingKey{}, true)
+}
+
+// UnknownFieldsError lists the response fields with no counterpart in the
+// response type. The response is still decoded.
+type UnknownFieldsError struct {
+	Path   string
+	Fields []string
+}
+
+func (e *UnknownFieldsError) Error() string {
+	return fmt.Sprintf("response from %s has unknown fields: %s", e.Path, strings.Join(e.Fields, ", "))
+}
+
+// UnknownFieldReport collects unknown response fields by path, without
+// failing calls, to detect schema drift. Set it as Config.UnknownFields.
+type UnknownFieldReport struct {
+	// MaxFields bounds the distinct path and field pairs kept, so a server
+	// echoing arbitrary keys cannot grow the report without limit. Defaults
+	// to 1000.
+	MaxFields int
+
+	mu      sync.Mutex
+	fields  map[string]map[string]bool
+	count   int
+	dropped uint64
+}
+
+// UnknownFieldStats summarizes an UnknownFieldReport.
+type UnknownFieldStats struct {
+	Paths   int
+	Fields  int
+	Dropped uint64
+}
+
+// Fields returns the unknown fields seen so far, sorted, by path template.
+func (r *UnknownFieldReport) Fields() map[string][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := make(map[string][]string, len(r.fields))
+	for path, fields := range r.fields {
+		for field := range fields {
+			report[path] = append(report[path], field)
+		}
+		sort.Strings(report[path])
+	}
+	return report
+}
+
+// Stats returns how many paths and fields were recorded, and how many new
+// fields were dropped once MaxFields was reached.
+func (r *UnknownFieldReport) Stats() UnknownFieldStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return UnknownFieldStats{Paths: len(r.fields), Fields: r.count, Dropped: r.dropped}
+}
+
+// Reset clears the report.
+func (r *UnknownFieldReport) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fields, r.count, r.dropped = nil, 0, 0
+}
+
+// record adds fields under path and returns those not seen before.
+func (r *UnknownFieldReport) record(path string, fields []string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	maxFields := r.MaxFields
+	if maxFields <= 0 {
+		maxFields = 1000
+	}
+	if r.fields == nil {
+		r.fields = map[string]map[string]bool{}
+	}
+
+	var added []string
+	for _, field := range fields {
+		if r.fields[path][field] {
+			continue
+		}
+		if r.count >= maxFields {
+			r.dropped++
+			continue
+		}
+		if r.fields[path] == nil {
+			r.fields[path] = map[string]bool{}
+		}
+		r.fields[path][field] = true
+		r.count++
+		added = append(added, field)
+	}
+	return added
+}
+
+// checkUnknownFields reports or rejects JSON fields in body that response
+// does not declare, when the call or client asks for it.
+func checkUnknownFields(ctx context.Context, config *Config, path, contentType string, body []byte, response interface{}) error {
+	strict, _ := ctx.Value(strictDecodingKey{}).(bool)
+	strict = strict || config.StrictDecoding
+	hook := config.Hooks != nil && config.Hooks.UnknownFields != nil
+	if !strict && config.UnknownFields == nil && !hook {
+		return nil
+	}
+
+	switch response.(type) {
+	case nil, *[]byte:
+		return nil
+	}
+	if _, ok := codecFor(contentType).(JsonCodec); !ok || len(body) == 0 {
+		return nil
+	}
+
+	fields := unknownJsonFields(body, reflect.TypeOf(response))
+	if len(fields) == 0 {
+		return nil
+	}
+
+	template := metricsPath(ctx, config, path)
+	added := fields
+	if config.UnknownFields != nil {
+		added = config.UnknownFields.record(template, fields)
+	}
+	if hook && len(added) > 0 {
+		config.Hooks.UnknownFields(ctx, template, added)
+	}
+	if strict {
+		return &UnknownFieldsError{Path: template, Fields: fields}
+	}
+	return nil
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	rawMessageType      = reflect.TypeOf(json.RawMessage(nil))
+)
+
+// unknownJsonFields returns the dotted paths, sorted, of fields in data that
+// have no counterpart in t. Array elements are marked "[]" and map values
+// "*". Types with their own UnmarshalJSON are not inspected.
+func unknownJsonFields(data []byte, t reflect.Type) []string {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	collectUnknownFields(v, t, "", seen)
+
+	fields := make([]string, 0, len(seen))
+	for field := range seen {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func collectUnknownFields(v interface{}, t reflect.Type, prefix string, seen map[string]bool) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == rawMessageType || reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := v.(map[string]interface{})
+		if !ok {
+			return
+		}
+		fields := jsonFieldsOf(t)
+		for name, value := range object {
+			fieldType, ok := lookupJsonField(fields, name)
+			if !ok {
+				seen[prefix+name] = true
+				continue
+			}
+			collectUnknownFields(value, fieldType, prefix+name+".", seen)
+		}
+	case reflect.Map:
+		object, ok := v.(map[string]interface{})
+		if !ok {
+			return
+		}
+		for _, value := range object {
+			collectUnknownFields(value, t.Elem(), prefix+"*.", seen)
+		}
+	case reflect.Slice, reflect.Array:
+		items, ok := v.([]interface{})
+		if !ok {
+			return
+		}
+		elemPrefix := strings.TrimSuffix(prefix, ".") + "[]."
+		for _, item := range items {
+			collectUnknownFields(item, t.Elem(), elemPrefix, seen)
+		}
+	}
+}
+
+// jsonFieldsOf returns the JSON names of t's fields, including those promoted
+// from embedded structs.
+func jsonFieldsOf(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		if f.Anonymous && name == "" {
+			embedded := f.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				for n, ft := range jsonFieldsOf(embedded) {
+					if _, ok := fields[n]; !ok {
+						fields[n] = ft
+					}
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// lookupJsonField matches name exactly and then case-insensitively, as
+// encoding/json does.
+func lookupJsonField(fields map[string]reflect.Type, name string) (reflect.Type, bool) {
+	if t, ok := fields[name]; ok {
+		return t, true
+	}
+	for n, t := range fields {
+		if strings.EqualFold(n, name) {
+			return t, true
+		}
+	}
+	return nil, false
+}
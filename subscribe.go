@@ -0,0 +1,273 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// SubscribeMessage is a websocket subscribe or unsubscribe payload, such as
+// {"type": "subscribe", "channel": "level2", "product_ids": ["BTC-USD"]}. It
+// is a map so the differing shapes of the Exchange, Advanced Trade, and INTX
+// feeds, and any extension fields, fit one type.
+type SubscribeMessage map[string]interface{}
+
+// Clone returns a shallow copy of m, so fields added to the copy do not
+// appear in m.
+func (m SubscribeMessage) Clone() SubscribeMessage {
+	cloned := make(SubscribeMessage, len(m))
+	for k, v := range m {
+		cloned[k] = v
+	}
+	return cloned
+}
+
+// AuthDecorator adds authentication or other per-message fields, such as a
+// jwt or api_key_name, to an outgoing subscribe payload. It is applied every
+// time the payload is encoded, so credentials are fresh when a subscription
+// is sent again after a reconnect.
+type AuthDecorator interface {
+	Decorate(ctx context.Context, message SubscribeMessage) error
+}
+
+// AuthDecoratorFunc adapts a function to an AuthDecorator.
+type AuthDecoratorFunc func(ctx context.Context, message SubscribeMessage) error
+
+func (f AuthDecoratorFunc) Decorate(ctx context.Context, message SubscribeMessage) error {
+	return f(ctx, message)
+}
+
+// StaticFieldsDecorator sets fields on every payload, e.g. an api_key_name
+// expected alongside a signature.
+func StaticFieldsDecorator(fields map[string]interface{}) AuthDecorator {
+	return AuthDecoratorFunc(func(ctx context.Context, message SubscribeMessage) error {
+		for k, v := range fields {
+			message[k] = v
+		}
+		return nil
+	})
+}
+
+// EncodeSubscribeMessage applies decorators, in order, to a copy of message
+// and returns it as JSON, ready to write to the connection. message itself
+// is left unchanged, so it can be kept and encoded again later.
+func EncodeSubscribeMessage(ctx context.Context, message SubscribeMessage, decorators ...AuthDecorator) ([]byte, error) {
+	decorated := message.Clone()
+	for _, decorator := range decorators {
+		if err := decorator.Decorate(ctx, decorated); err != nil {
+			return nil, fmt.Errorf("decorating subscribe message: %w", err)
+		}
+	}
+	return json.Marshal(decorated)
+}
+
+// SubscriptionManager tracks the subscriptions active on a websocket feed and
+// replays them after a reconnect. Subscriptions with a "channel" field, the
+// Advanced Trade and INTX shape, are tracked per channel and product id, so
+// an unsubscribe for some products keeps the rest. Other payloads, such as
+// Exchange's "channels" list, are tracked as a whole and removed by an
+// unsubscribe with the same fields. It is safe for concurrent use.
+type SubscriptionManager struct {
+	// Send writes a payload to the current connection.
+	Send func(ctx context.Context, payload []byte) error
+
+	// Decorators are applied each time a payload is encoded, so
+	// authenticated subscriptions are signed afresh when replayed.
+	Decorators []AuthDecorator
+
+	mu      sync.Mutex
+	tracked []*trackedSubscription
+}
+
+type trackedSubscription struct {
+	// channel is empty for payloads tracked as a whole, which are then
+	// identified by key.
+	channel    string
+	key        string
+	message    SubscribeMessage
+	productIds []string
+}
+
+// Subscribe sends message and, once sent, tracks it for Resubscribe.
+func (m *SubscriptionManager) Subscribe(ctx context.Context, message SubscribeMessage) error {
+	if err := m.send(ctx, message); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	channel, ok := message["channel"].(string)
+	if !ok {
+		key := subscriptionKey(message)
+		for _, t := range m.tracked {
+			if t.channel == "" && t.key == key {
+				return nil
+			}
+		}
+		m.tracked = append(m.tracked, &trackedSubscription{key: key, message: message.Clone()})
+		return nil
+	}
+
+	t := m.channel(channel)
+	if t == nil {
+		base := message.Clone()
+		delete(base, "product_ids")
+		t = &trackedSubscription{channel: channel, message: base}
+		m.tracked = append(m.tracked, t)
+	}
+	for _, id := range productIdsOf(message) {
+		if !containsString(t.productIds, id) {
+			t.productIds = append(t.productIds, id)
+		}
+	}
+	return nil
+}
+
+// Unsubscribe sends message and stops tracking what it unsubscribes from.
+// For a channel payload without product ids, the whole channel is dropped.
+func (m *SubscriptionManager) Unsubscribe(ctx context.Context, message SubscribeMessage) error {
+	if err := m.send(ctx, message); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	channel, ok := message["channel"].(string)
+	if !ok {
+		key := subscriptionKey(message)
+		m.remove(func(t *trackedSubscription) bool { return t.channel == "" && t.key == key })
+		return nil
+	}
+
+	t := m.channel(channel)
+	if t == nil {
+		return nil
+	}
+	if ids := productIdsOf(message); len(ids) > 0 {
+		kept := t.productIds[:0]
+		for _, id := range t.productIds {
+			if !containsString(ids, id) {
+				kept = append(kept, id)
+			}
+		}
+		t.productIds = kept
+		if len(kept) > 0 {
+			return nil
+		}
+	}
+	m.remove(func(tracked *trackedSubscription) bool { return tracked == t })
+	return nil
+}
+
+// Resubscribe sends every tracked subscription again, in the order they were
+// first made, e.g. right after a reconnect. It stops at the first failure.
+func (m *SubscriptionManager) Resubscribe(ctx context.Context) error {
+	for _, message := range m.Active() {
+		if err := m.send(ctx, message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Active returns the tracked subscriptions as subscribe payloads.
+func (m *SubscriptionManager) Active() []SubscribeMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	active := make([]SubscribeMessage, 0, len(m.tracked))
+	for _, t := range m.tracked {
+		message := t.message.Clone()
+		message["type"] = "subscribe"
+		if t.channel != "" && len(t.productIds) > 0 {
+			message["product_ids"] = append([]string(nil), t.productIds...)
+		}
+		active = append(active, message)
+	}
+	return active
+}
+
+func (m *SubscriptionManager) send(ctx context.Context, message SubscribeMessage) error {
+	payload, err := EncodeSubscribeMessage(ctx, message, m.Decorators...)
+	if err != nil {
+		return err
+	}
+	return m.Send(ctx, payload)
+}
+
+// channel returns the tracked subscription for channel. m.mu must be held.
+func (m *SubscriptionManager) channel(channel string) *trackedSubscription {
+	for _, t := range m.tracked {
+		if t.channel == channel {
+			return t
+		}
+	}
+	return nil
+}
+
+// remove drops the tracked subscriptions matching drop. m.mu must be held.
+func (m *SubscriptionManager) remove(drop func(t *trackedSubscription) bool) {
+	kept := m.tracked[:0]
+	for _, t := range m.tracked {
+		if !drop(t) {
+			kept = append(kept, t)
+		}
+	}
+	m.tracked = kept
+}
+
+// subscriptionKey identifies a payload tracked as a whole by every field but
+// its type.
+func subscriptionKey(message SubscribeMessage) string {
+	keyed := message.Clone()
+	delete(keyed, "type")
+	key, _ := json.Marshal(keyed)
+	return string(key)
+}
+
+// productIdsOf returns the "product_ids" of message, whether built in Go or
+// decoded from JSON.
+func productIdsOf(message SubscribeMessage) []string {
+	switch ids := message["product_ids"].(type) {
+	case []string:
+		return ids
+	case []interface{}:
+		productIds := make([]string, 0, len(ids))
+		for _, id := range ids {
+			if s, ok := id.(string); ok {
+				productIds = append(productIds, s)
+			}
+		}
+		return productIds
+	default:
+		return nil
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
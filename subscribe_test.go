@@ -0,0 +1,97 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestEncodeSubscribeMessageAppliesDecoratorsToCopy(t *testing.T) {
+	message := SubscribeMessage{"type": "subscribe", "channel": "user", "product_ids": []string{"BTC-USD"}}
+	tokens := 0
+	decorators := []AuthDecorator{
+		StaticFieldsDecorator(map[string]interface{}{"api_key_name": "key-1"}),
+		AuthDecoratorFunc(func(ctx context.Context, message SubscribeMessage) error {
+			tokens++
+			message["token"] = tokens
+			return nil
+		}),
+	}
+
+	for want := 1; want <= 2; want++ {
+		data, err := EncodeSubscribeMessage(context.Background(), message, decorators...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded map[string]interface{}
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if decoded["api_key_name"] != "key-1" || decoded["token"] != float64(want) || decoded["channel"] != "user" {
+			t.Fatalf("encoding %d = %s, want the channel, api_key_name, and token %d", want, data, want)
+		}
+	}
+
+	if _, ok := message["token"]; ok {
+		t.Fatal("decorators modified the caller's message")
+	}
+}
+
+func TestEncodeSubscribeMessageReportsDecoratorErrors(t *testing.T) {
+	failure := errors.New("no credentials")
+	_, err := EncodeSubscribeMessage(context.Background(), SubscribeMessage{"type": "subscribe"},
+		AuthDecoratorFunc(func(context.Context, SubscribeMessage) error { return failure }))
+	if !errors.Is(err, failure) {
+		t.Fatalf("err = %v, want the decorator's error", err)
+	}
+}
+
+func TestSubscriptionManagerTracksExchangePayloadsWhole(t *testing.T) {
+	m := &SubscriptionManager{Send: func(context.Context, []byte) error { return nil }}
+	ctx := context.Background()
+	channels := []interface{}{"heartbeat", map[string]interface{}{"name": "ticker", "product_ids": []string{"BTC-USD"}}}
+
+	if err := m.Subscribe(ctx, SubscribeMessage{"type": "subscribe", "channels": channels}); err != nil {
+		t.Fatal(err)
+	}
+	if active := m.Active(); len(active) != 1 || active[0]["type"] != "subscribe" {
+		t.Fatalf("active = %v, want the subscribe payload", active)
+	}
+
+	if err := m.Unsubscribe(ctx, SubscribeMessage{"type": "unsubscribe", "channels": channels}); err != nil {
+		t.Fatal(err)
+	}
+	if active := m.Active(); len(active) != 0 {
+		t.Fatalf("active after unsubscribing = %v, want none", active)
+	}
+}
+
+func TestSubscriptionManagerDoesNotTrackFailedSends(t *testing.T) {
+	failure := errors.New("connection closed")
+	m := &SubscriptionManager{Send: func(context.Context, []byte) error { return failure }}
+
+	err := m.Subscribe(context.Background(), SubscribeMessage{"type": "subscribe", "channel": "ticker", "product_ids": []string{"BTC-USD"}})
+	if !errors.Is(err, failure) {
+		t.Fatalf("err = %v, want the send error", err)
+	}
+	if active := m.Active(); len(active) != 0 {
+		t.Fatalf("active = %v after a failed subscribe, want none", active)
+	}
+}
@@ -0,0 +1,190 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Trade is one print on a trade tape. Price and Size keep the exchange's
+// decimal strings, so nothing is lost to floating point.
+type Trade struct {
+	Time      time.Time `parquet:"time"`
+	ProductId string    `parquet:"product_id"`
+	TradeId   string    `parquet:"trade_id"`
+	Side      string    `parquet:"side"`
+	Price     string    `parquet:"price"`
+	Size      string    `parquet:"size"`
+}
+
+// TapeWriter encodes trades into one file. Close flushes the encoding but
+// does not close the underlying file.
+type TapeWriter interface {
+	Write(trade Trade) error
+	Close() error
+}
+
+// NewCsvTapeWriter returns a TapeWriter producing CSV with a header row and
+// RFC 3339 nanosecond timestamps.
+func NewCsvTapeWriter(w io.Writer) (TapeWriter, error) {
+	tw := &csvTapeWriter{w: csv.NewWriter(w)}
+	if err := tw.w.Write([]string{"time", "product_id", "trade_id", "side", "price", "size"}); err != nil {
+		return nil, err
+	}
+	return tw, nil
+}
+
+type csvTapeWriter struct {
+	w *csv.Writer
+}
+
+func (t *csvTapeWriter) Write(trade Trade) error {
+	return t.w.Write([]string{
+		trade.Time.UTC().Format(time.RFC3339Nano),
+		trade.ProductId,
+		trade.TradeId,
+		trade.Side,
+		trade.Price,
+		trade.Size,
+	})
+}
+
+func (t *csvTapeWriter) Close() error {
+	t.w.Flush()
+	return t.w.Error()
+}
+
+// TapeRecorder writes trades to files in Dir, starting a new file every
+// RotateEvery. Files are named Prefix plus the UTC time they were opened.
+// A Parquet writer is available with -tags parquet.
+type TapeRecorder struct {
+	Dir    string
+	Prefix string
+
+	// RotateEvery is how long a file is written before the next is started.
+	// Defaults to one hour.
+	RotateEvery time.Duration
+
+	// NewWriter encodes each file. Defaults to NewCsvTapeWriter.
+	NewWriter func(w io.Writer) (TapeWriter, error)
+
+	// Extension is appended to file names. Defaults to ".csv".
+	Extension string
+
+	mu     sync.Mutex
+	file   *os.File
+	writer TapeWriter
+	opened time.Time
+}
+
+// Record appends trade to the current file, rotating first when it is due.
+func (r *TapeRecorder) Record(trade Trade) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if r.writer == nil || now.Sub(r.opened) >= r.rotateEvery() {
+		if err := r.rotate(now); err != nil {
+			return err
+		}
+	}
+	return r.writer.Write(trade)
+}
+
+// RecordFrom records every Trade or *Trade event on sub until ctx is done or
+// sub is closed. Write failures are passed to onError when it is set.
+func (r *TapeRecorder) RecordFrom(ctx context.Context, sub *Subscription, onError func(err error)) {
+	Bridge(ctx, sub, EventSinkFunc(func(ctx context.Context, event Event) error {
+		switch trade := event.Payload.(type) {
+		case Trade:
+			return r.Record(trade)
+		case *Trade:
+			return r.Record(*trade)
+		default:
+			return nil
+		}
+	}), func(event Event, err error) {
+		if onError != nil {
+			onError(err)
+		}
+	})
+}
+
+// Close finishes the current file.
+func (r *TapeRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.closeFile()
+}
+
+// rotate closes the current file and opens the next. r.mu must be held.
+func (r *TapeRecorder) rotate(now time.Time) error {
+	if err := r.closeFile(); err != nil {
+		return err
+	}
+
+	extension := r.Extension
+	if extension == "" {
+		extension = ".csv"
+	}
+	path := filepath.Join(r.Dir, r.Prefix+now.UTC().Format("20060102T150405.000Z")+extension)
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening trade tape: %w", err)
+	}
+
+	newWriter := r.NewWriter
+	if newWriter == nil {
+		newWriter = NewCsvTapeWriter
+	}
+	writer, err := newWriter(file)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	r.file, r.writer, r.opened = file, writer, now
+	return nil
+}
+
+// closeFile finishes the current file, if any. r.mu must be held.
+func (r *TapeRecorder) closeFile() error {
+	if r.writer == nil {
+		return nil
+	}
+	err := r.writer.Close()
+	if closeErr := r.file.Close(); err == nil {
+		err = closeErr
+	}
+	r.file, r.writer = nil, nil
+	return err
+}
+
+func (r *TapeRecorder) rotateEvery() time.Duration {
+	if r.RotateEvery <= 0 {
+		return time.Hour
+	}
+	return r.RotateEvery
+}
@@ -0,0 +1,45 @@
+//go:build parquet
+
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"io"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// NewParquetTapeWriter returns a TapeWriter producing Parquet, with prices
+// and sizes as strings. Use it with a TapeRecorder Extension of ".parquet".
+// It is only built with -tags parquet.
+func NewParquetTapeWriter(w io.Writer) (TapeWriter, error) {
+	return &parquetTapeWriter{w: parquet.NewGenericWriter[Trade](w)}, nil
+}
+
+type parquetTapeWriter struct {
+	w *parquet.GenericWriter[Trade]
+}
+
+func (t *parquetTapeWriter) Write(trade Trade) error {
+	_, err := t.w.Write([]Trade{trade})
+	return err
+}
+
+func (t *parquetTapeWriter) Close() error {
+	return t.w.Close()
+}
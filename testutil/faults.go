@@ -0,0 +1,177 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package testutil provides helpers for testing code built on core.
+package testutil
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Faults sets how often each fault is injected, as a rate from 0 to 1.
+type Faults struct {
+	// LatencyRate delays requests by Latency before sending them.
+	LatencyRate float64
+	Latency     time.Duration
+
+	// ServerErrorRate answers with ServerErrorStatus (default 503) without
+	// sending the request.
+	ServerErrorRate   float64
+	ServerErrorStatus int
+
+	// ResetRate fails the request with a connection reset.
+	ResetRate float64
+
+	// MalformedJsonRate sends the request and truncates the response body so
+	// it no longer parses.
+	MalformedJsonRate float64
+
+	// Seed makes the injected sequence reproducible. Zero seeds from the clock.
+	Seed int64
+}
+
+// FaultStats counts the faults injected so far.
+type FaultStats struct {
+	Requests      int
+	Delayed       int
+	ServerErrors  int
+	Resets        int
+	MalformedJson int
+}
+
+// FaultInjector is an http.RoundTripper that injects latency, 5xx responses,
+// connection resets, and malformed JSON in front of another transport. Set it
+// as the Transport of the client's *http.Client in tests. It sits below core
+// rather than being a Middleware so that every fault goes through the same
+// error classification, response parsing, retries, and fallback as a real
+// network failure.
+type FaultInjector struct {
+	next   http.RoundTripper
+	faults Faults
+
+	mu    sync.Mutex
+	rand  *rand.Rand
+	stats FaultStats
+}
+
+// NewFaultInjector wraps next, or http.DefaultTransport when nil.
+func NewFaultInjector(next http.RoundTripper, faults Faults) *FaultInjector {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if faults.ServerErrorStatus == 0 {
+		faults.ServerErrorStatus = http.StatusServiceUnavailable
+	}
+	seed := faults.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &FaultInjector{next: next, faults: faults, rand: rand.New(rand.NewSource(seed))}
+}
+
+func (f *FaultInjector) RoundTrip(req *http.Request) (*http.Response, error) {
+	delay, serverError, reset, malformed := f.roll()
+
+	if delay {
+		timer := time.NewTimer(f.faults.Latency)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+
+	if reset {
+		return nil, &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+	}
+
+	if serverError {
+		body := []byte(`{"message":"injected fault"}`)
+		return &http.Response{
+			Status:        statusLine(f.faults.ServerErrorStatus),
+			StatusCode:    f.faults.ServerErrorStatus,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": {"application/json"}},
+			Body:          io.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	}
+
+	res, err := f.next.RoundTrip(req)
+	if err != nil || !malformed {
+		return res, err
+	}
+
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	truncated := append(body[:len(body)/2:len(body)/2], []byte(`{"`)...)
+	res.Body = io.NopCloser(bytes.NewReader(truncated))
+	res.ContentLength = int64(len(truncated))
+	res.Header.Del("Content-Length")
+	return res, nil
+}
+
+func (f *FaultInjector) Stats() FaultStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stats
+}
+
+func (f *FaultInjector) roll() (delay, serverError, reset, malformed bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.stats.Requests++
+	delay = f.rand.Float64() < f.faults.LatencyRate
+	reset = f.rand.Float64() < f.faults.ResetRate
+	serverError = !reset && f.rand.Float64() < f.faults.ServerErrorRate
+	malformed = !reset && !serverError && f.rand.Float64() < f.faults.MalformedJsonRate
+
+	if delay {
+		f.stats.Delayed++
+	}
+	if reset {
+		f.stats.Resets++
+	}
+	if serverError {
+		f.stats.ServerErrors++
+	}
+	if malformed {
+		f.stats.MalformedJson++
+	}
+	return delay, serverError, reset, malformed
+}
+
+// statusLine returns the Status of a response with code, such as
+// "503 Service Unavailable", as net/http sets it.
+func statusLine(code int) string {
+	return fmt.Sprintf("%d %s", code, http.StatusText(code))
+}
@@ -0,0 +1,206 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package testutil
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// UpdateGoldenEnv names the environment variable that, when set to 1, makes
+// golden assertions rewrite their files instead of comparing.
+const UpdateGoldenEnv = "CORE_UPDATE_GOLDEN"
+
+// CapturedRequest is an outgoing request as it left the client.
+type CapturedRequest struct {
+	Method string
+	Path   string
+	Query  string
+	Header http.Header
+	Body   []byte
+}
+
+// RecordingTransport is an http.RoundTripper that records every request and
+// answers with a fixed response, for inspecting exactly what core sends.
+type RecordingTransport struct {
+	StatusCode int
+	Body       string
+
+	mu       sync.Mutex
+	requests []*CapturedRequest
+}
+
+// NewRecordingTransport answers every request with statusCode and body.
+func NewRecordingTransport(statusCode int, body string) *RecordingTransport {
+	return &RecordingTransport{StatusCode: statusCode, Body: body}
+}
+
+func (t *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+
+	t.mu.Lock()
+	t.requests = append(t.requests, &CapturedRequest{
+		Method: req.Method,
+		Path:   req.URL.EscapedPath(),
+		Query:  req.URL.RawQuery,
+		Header: req.Header.Clone(),
+		Body:   body,
+	})
+	t.mu.Unlock()
+
+	return &http.Response{
+		Status:        statusLine(t.StatusCode),
+		StatusCode:    t.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(strings.NewReader(t.Body)),
+		ContentLength: int64(len(t.Body)),
+		Request:       req,
+	}, nil
+}
+
+// Requests returns every captured request in order.
+func (t *RecordingTransport) Requests() []*CapturedRequest {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*CapturedRequest(nil), t.requests...)
+}
+
+// Last returns the most recent request, or nil.
+func (t *RecordingTransport) Last() *CapturedRequest {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.requests) == 0 {
+		return nil
+	}
+	return t.requests[len(t.requests)-1]
+}
+
+// RenderOptions controls how a request is rendered for golden comparison.
+type RenderOptions struct {
+	// MaskHeaders are replaced by "<masked>", e.g. headers carrying values
+	// that legitimately change between runs.
+	MaskHeaders []string
+
+	// Secrets are replaced by "<secret>" wherever they appear, so golden
+	// files never contain key material.
+	Secrets []string
+
+	// CanonicalString, when set, renders the string the signature was
+	// computed over, so a mismatch shows whether the input or the algorithm
+	// changed.
+	CanonicalString func(req *CapturedRequest) string
+}
+
+// RenderRequest renders req as text: the request line, sorted headers, the
+// canonical string when configured, and the body.
+func RenderRequest(req *CapturedRequest, opts RenderOptions) string {
+	masked := make(map[string]bool, len(opts.MaskHeaders))
+	for _, h := range opts.MaskHeaders {
+		masked[http.CanonicalHeaderKey(h)] = true
+	}
+
+	var b strings.Builder
+	b.WriteString(req.Method + " " + req.Path)
+	if req.Query != "" {
+		b.WriteString("?" + req.Query)
+	}
+	b.WriteString("\n")
+
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range req.Header[name] {
+			if masked[http.CanonicalHeaderKey(name)] {
+				value = "<masked>"
+			}
+			b.WriteString(name + ": " + value + "\n")
+		}
+	}
+
+	if opts.CanonicalString != nil {
+		b.WriteString("\n-- canonical --\n")
+		b.WriteString(opts.CanonicalString(req))
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n-- body --\n")
+	b.Write(req.Body)
+	b.WriteString("\n")
+
+	rendered := b.String()
+	for _, secret := range opts.Secrets {
+		if secret != "" {
+			rendered = strings.ReplaceAll(rendered, secret, "<secret>")
+		}
+	}
+	return rendered
+}
+
+// AssertGolden compares got with the file at path, failing the test with both
+// versions on mismatch. With CORE_UPDATE_GOLDEN=1 the file is rewritten.
+func AssertGolden(t testing.TB, path string, got string) {
+	t.Helper()
+
+	if os.Getenv(UpdateGoldenEnv) == "1" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("creating golden directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("writing golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file %s (set %s=1 to create it): %v", path, UpdateGoldenEnv, err)
+	}
+	if !bytes.Equal(want, []byte(got)) {
+		t.Errorf("request does not match golden file %s\n--- want\n%s\n--- got\n%s", path, want, got)
+	}
+}
+
+// AssertSignedRequestGolden renders req with opts and compares it with the
+// golden file at path.
+func AssertSignedRequestGolden(t testing.TB, path string, req *CapturedRequest, opts RenderOptions) {
+	t.Helper()
+
+	if req == nil {
+		t.Fatalf("no request captured for golden file %s", path)
+	}
+	AssertGolden(t, path, RenderRequest(req, opts))
+}
@@ -0,0 +1,168 @@
+//go:build sandbox
+
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package testutil
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/coinbase-samples/core-go"
+)
+
+// SandboxCredentialsEnv names the environment variable holding sandbox
+// credentials as JSON, in the same shape as core.Credentials.
+const SandboxCredentialsEnv = "CORE_SANDBOX_CREDENTIALS"
+
+// SandboxCredentials reads credentials from CORE_SANDBOX_CREDENTIALS, skipping
+// the test when the variable is unset so the suite is safe to run anywhere.
+func SandboxCredentials(t testing.TB) *core.Credentials {
+	t.Helper()
+
+	raw := os.Getenv(SandboxCredentialsEnv)
+	if raw == "" {
+		t.Skipf("%s is not set", SandboxCredentialsEnv)
+	}
+
+	credentials := &core.Credentials{}
+	if err := json.Unmarshal([]byte(raw), credentials); err != nil {
+		t.Fatalf("parsing %s: %v", SandboxCredentialsEnv, err)
+	}
+	return credentials
+}
+
+// SandboxSuite is a standard battery of calls run against a sandbox, for SDKs
+// to check signing, pagination, and error mapping end to end. Each check runs
+// as a subtest and is skipped when the paths it needs are not set.
+type SandboxSuite struct {
+	Client      core.Client
+	HeadersFunc core.HeaderFunc
+
+	// AuthenticatedPath is a GET endpoint that rejects unsigned requests.
+	AuthenticatedPath string
+
+	// PaginatedPath is a GET endpoint returning at least two pages. Paginate
+	// returns the query for the page after body, or "" on the last page.
+	PaginatedPath string
+	Paginate      func(body []byte) (string, error)
+
+	// NotFoundPath is a GET endpoint that answers 404.
+	NotFoundPath string
+
+	// Timeout bounds each call. Defaults to 30 seconds.
+	Timeout time.Duration
+}
+
+// Run executes every check in the suite.
+func (s SandboxSuite) Run(t *testing.T) {
+	t.Run("Signing", s.testSigning)
+	t.Run("Pagination", s.testPagination)
+	t.Run("ErrorMapping", s.testErrorMapping)
+	t.Run("WebsocketSubscribe", func(t *testing.T) {
+		t.Skip("core does not provide a websocket client")
+	})
+}
+
+func (s SandboxSuite) testSigning(t *testing.T) {
+	if s.AuthenticatedPath == "" {
+		t.Skip("AuthenticatedPath is not set")
+	}
+
+	ctx, cancel := s.context()
+	defer cancel()
+
+	if err := core.HttpGet(ctx, s.Client, s.AuthenticatedPath, core.EmptyQueryParams, nil, nil, s.HeadersFunc); err != nil {
+		t.Fatalf("signed request was rejected: %v", err)
+	}
+
+	unsigned := func(req *http.Request, path string, body []byte, client core.Client, t time.Time) {}
+	err := core.HttpGet(ctx, s.Client, s.AuthenticatedPath, core.EmptyQueryParams, nil, nil, unsigned)
+	var apiErr *core.ApiError
+	if !errors.As(err, &apiErr) || (apiErr.CodeReceived != http.StatusUnauthorized && apiErr.CodeReceived != http.StatusForbidden) {
+		t.Fatalf("unsigned request: expected 401 or 403, got %v", err)
+	}
+}
+
+func (s SandboxSuite) testPagination(t *testing.T) {
+	if s.PaginatedPath == "" || s.Paginate == nil {
+		t.Skip("PaginatedPath or Paginate is not set")
+	}
+
+	ctx, cancel := s.context()
+	defer cancel()
+
+	query := core.EmptyQueryParams
+	seen := map[string]bool{}
+	for page := 1; ; page++ {
+		var body []byte
+		if err := core.HttpGet(ctx, s.Client, s.PaginatedPath, query, nil, &body, s.HeadersFunc); err != nil {
+			t.Fatalf("page %d: %v", page, err)
+		}
+
+		next, err := s.Paginate(body)
+		if err != nil {
+			t.Fatalf("page %d: reading cursor: %v", page, err)
+		}
+		if next == "" {
+			if page < 2 {
+				t.Fatalf("expected at least two pages, got %d", page)
+			}
+			return
+		}
+		if seen[next] {
+			t.Fatalf("page %d: cursor %q repeated", page, next)
+		}
+		seen[next] = true
+		query = next
+	}
+}
+
+func (s SandboxSuite) testErrorMapping(t *testing.T) {
+	if s.NotFoundPath == "" {
+		t.Skip("NotFoundPath is not set")
+	}
+
+	ctx, cancel := s.context()
+	defer cancel()
+
+	err := core.HttpGet(ctx, s.Client, s.NotFoundPath, core.EmptyQueryParams, nil, nil, s.HeadersFunc)
+
+	var apiErr *core.ApiError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *core.ApiError, got %T: %v", err, err)
+	}
+	if apiErr.CodeReceived != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", apiErr.CodeReceived)
+	}
+	if apiErr.Message == "" {
+		t.Error("expected the error message to be populated from the response")
+	}
+}
+
+func (s SandboxSuite) context() (context.Context, context.CancelFunc) {
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
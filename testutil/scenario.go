@@ -0,0 +1,333 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package testutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coinbase-samples/core-go"
+)
+
+// ErrScenarioConnClosed is returned by a ScriptedConn that was closed by the
+// client, or replaced after the script dropped it.
+var ErrScenarioConnClosed = errors.New("scripted connection closed")
+
+// CloseError is returned by ScriptedConn.ReadMessage when the script drops
+// the connection, carrying the websocket close code, e.g. 1006 for an
+// abnormal closure.
+type CloseError struct {
+	Code int
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("scripted connection dropped with close code %d", e.Code)
+}
+
+type stepKind int
+
+const (
+	stepSend stepKind = iota
+	stepPause
+	stepDrop
+	stepReconnect
+	stepExpectWrite
+)
+
+type scenarioStep struct {
+	kind     stepKind
+	messages []string
+	pause    time.Duration
+	code     int
+	describe string
+	match    func(data []byte) error
+}
+
+// Scenario scripts the server side of a websocket feed for testing reconnect
+// and resubscribe logic. Steps run in order: Send and Pause are played out
+// by the client's reads, Drop fails the next read, Reconnect admits one more
+// Dial, and ExpectWrite consumes the client's next write. The connection is
+// a plain message interface, so the code under test should reach its
+// websocket library through a small adapter that ScriptedConn also
+// satisfies.
+//
+//	s := testutil.NewScenario(t).
+//		ExpectWrite(`{"type":"subscribe","channel":"ticker"}`).
+//		Send(tick1, tick2).
+//		Drop(1006).
+//		Reconnect().
+//		ExpectWrite(`{"type":"subscribe","channel":"ticker"}`)
+type Scenario struct {
+	// Clock times Pause steps when it is a core.TimerClock, such as a
+	// FakeClock. Pauses otherwise use real time.
+	Clock core.Clock
+
+	t       testing.TB
+	mu      sync.Mutex
+	steps   []scenarioStep
+	pos     int
+	sent    int
+	current *ScriptedConn
+	dials   int
+	changed chan struct{}
+}
+
+// NewScenario returns an empty scenario reporting mismatched writes to t.
+func NewScenario(t testing.TB) *Scenario {
+	return &Scenario{t: t, changed: make(chan struct{})}
+}
+
+// Send has the server send messages, one per client read.
+func (s *Scenario) Send(messages ...string) *Scenario {
+	return s.add(scenarioStep{kind: stepSend, messages: messages})
+}
+
+// Pause delays the next step by d.
+func (s *Scenario) Pause(d time.Duration) *Scenario {
+	return s.add(scenarioStep{kind: stepPause, pause: d})
+}
+
+// Drop fails the client's next read with a *CloseError carrying code, and
+// closes the connection.
+func (s *Scenario) Drop(code int) *Scenario {
+	return s.add(scenarioStep{kind: stepDrop, code: code})
+}
+
+// Reconnect admits the client's next Dial. Any Dial other than the first
+// fails unless the script has reached a Reconnect step.
+func (s *Scenario) Reconnect() *Scenario {
+	return s.add(scenarioStep{kind: stepReconnect})
+}
+
+// ExpectWrite requires the client's next write to be payload. Payloads are
+// compared as JSON when both parse, and byte for byte otherwise.
+func (s *Scenario) ExpectWrite(payload string) *Scenario {
+	return s.ExpectWriteFunc(payload, func(data []byte) error {
+		if !equalPayloads([]byte(payload), data) {
+			return fmt.Errorf("wrote %s, want %s", data, payload)
+		}
+		return nil
+	})
+}
+
+// ExpectWriteFunc requires the client's next write to pass match, for
+// payloads that change on every send, such as ones carrying a fresh token.
+// describe names the expected write in failures.
+func (s *Scenario) ExpectWriteFunc(describe string, match func(data []byte) error) *Scenario {
+	return s.add(scenarioStep{kind: stepExpectWrite, describe: describe, match: match})
+}
+
+func (s *Scenario) add(step scenarioStep) *Scenario {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.steps = append(s.steps, step)
+	return s
+}
+
+// Dial returns the next scripted connection: the first call always
+// succeeds, and later ones need the script to be at a Reconnect step.
+func (s *Scenario) Dial(ctx context.Context) (*ScriptedConn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.dials > 0 {
+		if s.pos == len(s.steps) || s.steps[s.pos].kind != stepReconnect {
+			return nil, fmt.Errorf("scenario: unexpected dial %d at %s", s.dials+1, s.describeLocked())
+		}
+		s.pos++
+	}
+	if s.current != nil {
+		s.current.closed = true
+	}
+	s.dials++
+	s.current = &ScriptedConn{scenario: s}
+	s.notifyLocked()
+	return s.current, nil
+}
+
+// Dials returns how many connections have been dialed.
+func (s *Scenario) Dials() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dials
+}
+
+// Wait blocks until every step has run, and returns an error naming the
+// pending step if that takes longer than timeout.
+func (s *Scenario) Wait(timeout time.Duration) error {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		s.mu.Lock()
+		done := s.pos == len(s.steps)
+		changed := s.changed
+		pending := s.describeLocked()
+		s.mu.Unlock()
+
+		if done {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-deadline.C:
+			return fmt.Errorf("scenario did not finish within %v: waiting at %s", timeout, pending)
+		}
+	}
+}
+
+// describeLocked names the pending step. s.mu must be held.
+func (s *Scenario) describeLocked() string {
+	if s.pos == len(s.steps) {
+		return "end of script"
+	}
+	step := s.steps[s.pos]
+	switch step.kind {
+	case stepSend:
+		return fmt.Sprintf("step %d (send %d of %d messages)", s.pos+1, s.sent+1, len(step.messages))
+	case stepPause:
+		return fmt.Sprintf("step %d (pause %v)", s.pos+1, step.pause)
+	case stepDrop:
+		return fmt.Sprintf("step %d (drop with %d)", s.pos+1, step.code)
+	case stepReconnect:
+		return fmt.Sprintf("step %d (reconnect)", s.pos+1)
+	default:
+		return fmt.Sprintf("step %d (expect write %s)", s.pos+1, step.describe)
+	}
+}
+
+// notifyLocked wakes goroutines waiting for the script to move. s.mu must
+// be held.
+func (s *Scenario) notifyLocked() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *Scenario) pause(d time.Duration) {
+	if tc, ok := s.Clock.(core.TimerClock); ok {
+		<-tc.NewTimer(d).C()
+		return
+	}
+	time.Sleep(d)
+}
+
+// ScriptedConn is one connection dialed from a Scenario.
+type ScriptedConn struct {
+	scenario *Scenario
+	closed   bool
+	dropped  *CloseError
+}
+
+// ReadMessage returns the next message the script sends. Once the script is
+// exhausted, or waiting for a write, it blocks like an idle connection
+// until the script moves on or the connection is closed.
+func (c *ScriptedConn) ReadMessage() ([]byte, error) {
+	s := c.scenario
+	for {
+		s.mu.Lock()
+		if c.dropped != nil {
+			s.mu.Unlock()
+			return nil, c.dropped
+		}
+		if c.closed {
+			s.mu.Unlock()
+			return nil, ErrScenarioConnClosed
+		}
+
+		if s.pos < len(s.steps) {
+			step := s.steps[s.pos]
+			switch step.kind {
+			case stepSend:
+				message := step.messages[s.sent]
+				if s.sent++; s.sent == len(step.messages) {
+					s.pos, s.sent = s.pos+1, 0
+				}
+				s.notifyLocked()
+				s.mu.Unlock()
+				return []byte(message), nil
+			case stepPause:
+				s.pos++
+				s.notifyLocked()
+				s.mu.Unlock()
+				s.pause(step.pause)
+				continue
+			case stepDrop:
+				s.pos++
+				c.dropped = &CloseError{Code: step.code}
+				s.notifyLocked()
+				s.mu.Unlock()
+				return nil, c.dropped
+			}
+		}
+
+		changed := s.changed
+		s.mu.Unlock()
+		<-changed
+	}
+}
+
+// WriteMessage checks data against the script's pending ExpectWrite step. A
+// write the script does not expect fails the test.
+func (c *ScriptedConn) WriteMessage(data []byte) error {
+	s := c.scenario
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c.closed || c.dropped != nil {
+		return ErrScenarioConnClosed
+	}
+	if s.pos == len(s.steps) || s.steps[s.pos].kind != stepExpectWrite {
+		s.t.Errorf("scenario: unexpected write %s at %s", data, s.describeLocked())
+		return nil
+	}
+
+	if err := s.steps[s.pos].match(data); err != nil {
+		s.t.Errorf("scenario: step %d: %v", s.pos+1, err)
+	}
+	s.pos++
+	s.notifyLocked()
+	return nil
+}
+
+// Close closes the connection, unblocking pending reads.
+func (c *ScriptedConn) Close() error {
+	s := c.scenario
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c.closed = true
+	s.notifyLocked()
+	return nil
+}
+
+func equalPayloads(want, got []byte) bool {
+	var w, g interface{}
+	if json.Unmarshal(want, &w) != nil || json.Unmarshal(got, &g) != nil {
+		return bytes.Equal(want, got)
+	}
+	return reflect.DeepEqual(w, g)
+}
@@ -0,0 +1,346 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package testutil
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coinbase-samples/core-go"
+)
+
+// SimUserTopic is the EventBus topic carrying order updates, standing in for
+// the user websocket channel.
+const SimUserTopic = "user"
+
+// SimOrder is an order held by a SimExchange.
+type SimOrder struct {
+	OrderId       string    `json:"order_id"`
+	ClientOrderId string    `json:"client_order_id"`
+	ProductId     string    `json:"product_id"`
+	Side          string    `json:"side"`
+	Size          string    `json:"size"`
+	Price         string    `json:"price"`
+	Status        string    `json:"status"`
+	CreatedTime   time.Time `json:"created_time"`
+}
+
+// SimExchange is an in-process exchange for end-to-end SDK tests. Orders are
+// placed, listed, fetched, and canceled over REST, and every change is
+// published on Events under SimUserTopic, so REST actions and the user feed
+// agree. SetConditions adds latency, rate limiting, and clock skew. Routes:
+//
+//	POST   /orders       place an order; a repeated client_order_id returns the original
+//	GET    /orders       list orders, paginated by limit and cursor
+//	GET    /orders/{id}  fetch an order
+//	DELETE /orders/{id}  cancel an open order
+//	GET    /time         server time as iso and epoch
+type SimExchange struct {
+	Server *httptest.Server
+	Events *core.EventBus
+
+	mu          sync.Mutex
+	conditions  SimConditions
+	windowStart time.Time
+	windowCount int
+
+	orders   map[string]*SimOrder
+	byClient map[string]string
+	sequence []string
+}
+
+// NewSimExchange starts an exchange. Close it when the test ends.
+func NewSimExchange() *SimExchange {
+	s := &SimExchange{
+		Events:   core.NewEventBus(),
+		orders:   map[string]*SimOrder{},
+		byClient: map[string]string{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Client returns a client for the exchange.
+func (s *SimExchange) Client() *core.RestClient {
+	return core.NewClient(s.Server.URL, s.Server.Client())
+}
+
+// Orders returns every order in placement order.
+func (s *SimExchange) Orders() []SimOrder {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	orders := make([]SimOrder, 0, len(s.sequence))
+	for _, id := range s.sequence {
+		orders = append(orders, *s.orders[id])
+	}
+	return orders
+}
+
+func (s *SimExchange) Close() {
+	s.Server.Close()
+}
+
+// SimConditions shapes how a SimExchange answers, to exercise retry,
+// throttling, and time sync handling.
+type SimConditions struct {
+	// Latency, when set, returns how long to delay each response, e.g. from
+	// FixedLatency, UniformLatency, or NormalLatency.
+	Latency func() time.Duration
+
+	// RateLimit answers 429 with Retry-After once more than RateLimit
+	// requests arrive within RateLimitWindow (default one second). Zero
+	// disables it.
+	RateLimit       int
+	RateLimitWindow time.Duration
+
+	// ClockSkew is added to the server's time in /time and the Date header.
+	ClockSkew time.Duration
+
+	// Clock drives rate limit windows and server time. Defaults to
+	// core.SystemClock; a FakeClock makes both deterministic.
+	Clock core.Clock
+}
+
+// SetConditions replaces the exchange's conditions.
+func (s *SimExchange) SetConditions(conditions SimConditions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conditions = conditions
+	s.windowStart = time.Time{}
+	s.windowCount = 0
+}
+
+// FixedLatency always returns d.
+func FixedLatency(d time.Duration) func() time.Duration {
+	return func() time.Duration { return d }
+}
+
+// UniformLatency returns delays spread evenly over [min, max), reproducibly
+// for a given seed.
+func UniformLatency(min, max time.Duration, seed int64) func() time.Duration {
+	var mu sync.Mutex
+	r := rand.New(rand.NewSource(seed))
+	return func() time.Duration {
+		mu.Lock()
+		defer mu.Unlock()
+		return min + time.Duration(r.Int63n(int64(max-min)+1))
+	}
+}
+
+// NormalLatency returns normally distributed delays, never negative,
+// reproducibly for a given seed.
+func NormalLatency(mean, stddev time.Duration, seed int64) func() time.Duration {
+	var mu sync.Mutex
+	r := rand.New(rand.NewSource(seed))
+	return func() time.Duration {
+		mu.Lock()
+		defer mu.Unlock()
+		return time.Duration(math.Max(0, r.NormFloat64()*float64(stddev)+float64(mean)))
+	}
+}
+
+// admit applies the conditions to a request, returning the server time and
+// how long a rate-limited request should wait, or zero when it is admitted.
+func (s *SimExchange) admit() (now time.Time, latency, retryAfter time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.conditions
+	clock := c.Clock
+	if clock == nil {
+		clock = core.SystemClock
+	}
+	local := clock.Now()
+	now = local.Add(c.ClockSkew)
+
+	if c.Latency != nil {
+		latency = c.Latency()
+	}
+
+	if c.RateLimit > 0 {
+		window := c.RateLimitWindow
+		if window <= 0 {
+			window = time.Second
+		}
+		if local.Sub(s.windowStart) >= window {
+			s.windowStart = local
+			s.windowCount = 0
+		}
+		s.windowCount++
+		if s.windowCount > c.RateLimit {
+			retryAfter = s.windowStart.Add(window).Sub(local)
+		}
+	}
+	return now, latency, retryAfter
+}
+
+func (s *SimExchange) serve(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimSuffix(r.URL.Path, "/")
+
+	now, latency, retryAfter := s.admit()
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		select {
+		case <-r.Context().Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+	w.Header().Set("Date", now.UTC().Format(http.TimeFormat))
+
+	if retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		writeSimError(w, http.StatusTooManyRequests, "rate limit exceeded")
+		return
+	}
+
+	switch {
+	case path == "/time" && r.Method == http.MethodGet:
+		now := now.UTC()
+		writeSimJson(w, http.StatusOK, map[string]interface{}{
+			"iso":   now.Format(time.RFC3339Nano),
+			"epoch": float64(now.UnixNano()) / 1e9,
+		})
+	case path == "/orders" && r.Method == http.MethodPost:
+		s.placeOrder(w, r)
+	case path == "/orders" && r.Method == http.MethodGet:
+		s.listOrders(w, r)
+	case strings.HasPrefix(path, "/orders/") && r.Method == http.MethodGet:
+		s.getOrder(w, strings.TrimPrefix(path, "/orders/"))
+	case strings.HasPrefix(path, "/orders/") && r.Method == http.MethodDelete:
+		s.cancelOrder(w, strings.TrimPrefix(path, "/orders/"))
+	default:
+		writeSimError(w, http.StatusNotFound, "route not found")
+	}
+}
+
+func (s *SimExchange) placeOrder(w http.ResponseWriter, r *http.Request) {
+	var order SimOrder
+	if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
+		writeSimError(w, http.StatusBadRequest, "invalid order: "+err.Error())
+		return
+	}
+	if order.ProductId == "" || order.Side == "" || order.Size == "" {
+		writeSimError(w, http.StatusBadRequest, "product_id, side, and size are required")
+		return
+	}
+
+	s.mu.Lock()
+	if id, ok := s.byClient[order.ClientOrderId]; ok && order.ClientOrderId != "" {
+		existing := *s.orders[id]
+		s.mu.Unlock()
+		writeSimJson(w, http.StatusOK, existing)
+		return
+	}
+
+	order.OrderId = strconv.Itoa(len(s.sequence) + 1)
+	order.Status = "OPEN"
+	order.CreatedTime = time.Now().UTC()
+	s.orders[order.OrderId] = &order
+	s.sequence = append(s.sequence, order.OrderId)
+	if order.ClientOrderId != "" {
+		s.byClient[order.ClientOrderId] = order.OrderId
+	}
+	placed := order
+	s.mu.Unlock()
+
+	s.Events.Publish(core.Event{Topic: SimUserTopic, Source: "sim", Payload: placed})
+	writeSimJson(w, http.StatusOK, placed)
+}
+
+func (s *SimExchange) listOrders(w http.ResponseWriter, r *http.Request) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 100
+	}
+	start, _ := strconv.Atoi(r.URL.Query().Get("cursor"))
+
+	orders := s.Orders()
+	if start > len(orders) {
+		start = len(orders)
+	}
+	end := start + limit
+	if end > len(orders) {
+		end = len(orders)
+	}
+
+	cursor := ""
+	if end < len(orders) {
+		cursor = strconv.Itoa(end)
+	}
+	writeSimJson(w, http.StatusOK, map[string]interface{}{
+		"orders":   orders[start:end],
+		"cursor":   cursor,
+		"has_next": cursor != "",
+	})
+}
+
+func (s *SimExchange) getOrder(w http.ResponseWriter, id string) {
+	s.mu.Lock()
+	order, ok := s.orders[id]
+	var found SimOrder
+	if ok {
+		found = *order
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeSimError(w, http.StatusNotFound, "order not found")
+		return
+	}
+	writeSimJson(w, http.StatusOK, found)
+}
+
+func (s *SimExchange) cancelOrder(w http.ResponseWriter, id string) {
+	s.mu.Lock()
+	order, ok := s.orders[id]
+	if !ok {
+		s.mu.Unlock()
+		writeSimError(w, http.StatusNotFound, "order not found")
+		return
+	}
+	if order.Status != "OPEN" {
+		s.mu.Unlock()
+		writeSimError(w, http.StatusBadRequest, "order is not open")
+		return
+	}
+	order.Status = "CANCELLED"
+	canceled := *order
+	s.mu.Unlock()
+
+	s.Events.Publish(core.Event{Topic: SimUserTopic, Source: "sim", Payload: canceled})
+	writeSimJson(w, http.StatusOK, canceled)
+}
+
+func writeSimJson(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeSimError(w http.ResponseWriter, status int, message string) {
+	writeSimJson(w, status, map[string]string{"message": message})
+}
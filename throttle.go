@@ -0,0 +1,109 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// BandwidthLimiter caps the combined read rate of every reader throttled by
+// it, so bulk downloads leave headroom for latency-sensitive traffic. Up to
+// one second's worth of bytes may be read in a burst.
+type BandwidthLimiter struct {
+	bytesPerSecond float64
+
+	mu        sync.Mutex
+	available float64
+	last      time.Time
+}
+
+// NewBandwidthLimiter returns a limiter allowing bytesPerSecond.
+func NewBandwidthLimiter(bytesPerSecond int64) *BandwidthLimiter {
+	if bytesPerSecond <= 0 {
+		panic("core: non-positive rate for NewBandwidthLimiter")
+	}
+	rate := float64(bytesPerSecond)
+	return &BandwidthLimiter{bytesPerSecond: rate, available: rate, last: time.Now()}
+}
+
+// Reader wraps r so reads wait for bandwidth. Waiting stops when ctx is done.
+func (l *BandwidthLimiter) Reader(ctx context.Context, r io.Reader) io.Reader {
+	return &throttledReader{ctx: ctx, r: r, limiter: l}
+}
+
+// ThrottleBody wraps body, typically a streamed response body, so reads wait
+// for bandwidth. Closing the result closes body.
+func (l *BandwidthLimiter) ThrottleBody(ctx context.Context, body io.ReadCloser) io.ReadCloser {
+	return struct {
+		io.Reader
+		io.Closer
+	}{l.Reader(ctx, body), body}
+}
+
+// wait takes n bytes from the bucket, sleeping until the balance is no longer
+// negative.
+func (l *BandwidthLimiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.available += now.Sub(l.last).Seconds() * l.bytesPerSecond
+	if l.available > l.bytesPerSecond {
+		l.available = l.bytesPerSecond
+	}
+	l.last = now
+	l.available -= float64(n)
+
+	var delay time.Duration
+	if l.available < 0 {
+		delay = time.Duration(-l.available / l.bytesPerSecond * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if delay > 0 && !sleepContext(ctx, delay) {
+		return ctx.Err()
+	}
+	return nil
+}
+
+func (l *BandwidthLimiter) burst() int {
+	if l.bytesPerSecond < 1 {
+		return 1
+	}
+	return int(l.bytesPerSecond)
+}
+
+type throttledReader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *BandwidthLimiter
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	if burst := t.limiter.burst(); len(p) > burst {
+		p = p[:burst]
+	}
+
+	n, err := t.r.Read(p)
+	if n > 0 {
+		if waitErr := t.limiter.wait(t.ctx, n); waitErr != nil && err == nil {
+			err = waitErr
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,148 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// ServerTimeFunc fetches the exchange's current time.
+type ServerTimeFunc func(ctx context.Context) (time.Time, error)
+
+// TimeSync estimates the offset between the local clock and an exchange's
+// clock, smoothing samples with an exponential moving average. As a Clock it
+// returns local time corrected by that offset, so signatures stay valid when
+// the host clock drifts.
+type TimeSync struct {
+	// Clock is the local clock being corrected, which also schedules Run.
+	// Defaults to SystemClock.
+	Clock Clock
+
+	fetch ServerTimeFunc
+	ema   *Ema
+
+	mu       sync.RWMutex
+	offset   time.Duration
+	lastSync time.Time
+}
+
+// NewTimeSync returns a TimeSync using fetch for samples. smoothing is the EMA
+// factor in (0, 1]; 1 uses only the latest sample. Any other value is an
+// error.
+func NewTimeSync(fetch ServerTimeFunc, smoothing float64) (*TimeSync, error) {
+	ema, err := NewEma(smoothing)
+	if err != nil {
+		return nil, err
+	}
+	return &TimeSync{fetch: fetch, ema: ema}, nil
+}
+
+// NewRestServerTimeFunc returns a ServerTimeFunc that GETs path on client and
+// reads the "iso" (RFC 3339) or "epoch" (seconds) field, the shape served by
+// Coinbase time endpoints.
+func NewRestServerTimeFunc(client Client, path string, headersFunc HeaderFunc) ServerTimeFunc {
+	return func(ctx context.Context) (time.Time, error) {
+		var response struct {
+			Iso   string  `json:"iso"`
+			Epoch float64 `json:"epoch"`
+		}
+		if err := HttpGet(ctx, client, path, EmptyQueryParams, nil, &response, headersFunc); err != nil {
+			return time.Time{}, err
+		}
+
+		if response.Iso != "" {
+			return time.Parse(time.RFC3339Nano, response.Iso)
+		}
+		if response.Epoch > 0 {
+			seconds, fraction := math.Modf(response.Epoch)
+			return time.Unix(int64(seconds), int64(fraction*1e9)), nil
+		}
+		return time.Time{}, errors.New("server time response has neither iso nor epoch")
+	}
+}
+
+// Sync takes one sample. The server time is assumed to correspond to the
+// midpoint of the round trip.
+func (t *TimeSync) Sync(ctx context.Context) error {
+	sent := t.clock().Now()
+	serverTime, err := t.fetch(ctx)
+	if err != nil {
+		return err
+	}
+	received := t.clock().Now()
+
+	midpoint := sent.Add(received.Sub(sent) / 2)
+	smoothed, err := t.ema.Add(float64(serverTime.Sub(midpoint)))
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.offset = time.Duration(smoothed)
+	t.lastSync = received
+	return nil
+}
+
+// Run syncs immediately and then every interval until ctx is done. Failures
+// are passed to onError when it is set.
+func (t *TimeSync) Run(ctx context.Context, interval time.Duration, onError func(err error)) {
+	ticker := newTicker(t.Clock, interval)
+	defer ticker.Stop()
+
+	for {
+		if err := t.Sync(ctx); err != nil && onError != nil && ctx.Err() == nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+// Now returns local time adjusted by the estimated offset.
+func (t *TimeSync) Now() time.Time {
+	return t.clock().Now().Add(t.Offset())
+}
+
+func (t *TimeSync) clock() Clock {
+	if t.Clock == nil {
+		return SystemClock
+	}
+	return t.Clock
+}
+
+// Offset is the estimated server time minus local time.
+func (t *TimeSync) Offset() time.Duration {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.offset
+}
+
+// LastSync returns when the last successful sample was taken.
+func (t *TimeSync) LastSync() time.Time {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.lastSync
+}
@@ -0,0 +1,266 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// staleConnectionMessages identify transport errors raised when the server
+// closed a pooled connection, which a new connection does not hit.
+var staleConnectionMessages = []string{
+	"http2: server sent GOAWAY",
+	"http2: client connection lost",
+	"http2: client connection force closed",
+	"server closed idle connection",
+}
+
+// doRequest sends req, retrying once on a fresh connection when an idempotent
+// request fails because the server closed a pooled connection, e.g. with an
+// HTTP/2 GOAWAY.
+func doRequest(client *http.Client, req *http.Request) (*http.Response, error) {
+	res, err := client.Do(req)
+	if err == nil || !isStaleConnectionError(err) || !isIdempotentMethod(req.Method) || req.Context().Err() != nil {
+		return res, err
+	}
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, bodyErr := req.GetBody()
+		if bodyErr != nil {
+			return nil, err
+		}
+		retry.Body = body
+	}
+
+	client.CloseIdleConnections()
+	return client.Do(retry)
+}
+
+func isStaleConnectionError(err error) bool {
+	if errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	message := err.Error()
+	for _, m := range staleConnectionMessages {
+		if strings.Contains(message, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewHttp1Transport returns a clone of base, or of http.DefaultTransport when
+// base is nil, that only speaks HTTP/1.1. It is the escape hatch for proxies
+// and middleboxes that break HTTP/2.
+func NewHttp1Transport(base *http.Transport) *http.Transport {
+	if base == nil {
+		base = http.DefaultTransport.(*http.Transport)
+	}
+
+	t := base.Clone()
+	t.ForceAttemptHTTP2 = false
+	t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	if t.TLSClientConfig == nil {
+		t.TLSClientConfig = &tls.Config{}
+	}
+	t.TLSClientConfig.NextProtos = []string{"http/1.1"}
+	return t
+}
+
+// Http2FallbackTransport sends requests over HTTP/2 until Threshold
+// consecutive HTTP/2 failures, then over HTTP/1.1 for Cooldown before trying
+// HTTP/2 again.
+type Http2FallbackTransport struct {
+	// Threshold is the number of consecutive failures that trigger the
+	// fallback. Defaults to 3.
+	Threshold int
+
+	// Cooldown is how long HTTP/1.1 is used before HTTP/2 is retried.
+	// Defaults to 5 minutes.
+	Cooldown time.Duration
+
+	// OnFallback, when set, is called each time the transport switches to
+	// HTTP/1.1, with the error that triggered it.
+	OnFallback func(err error)
+
+	http2 http.RoundTripper
+	http1 http.RoundTripper
+
+	mu            sync.Mutex
+	failures      int
+	fallbackUntil time.Time
+}
+
+// NewHttp2FallbackTransport builds both transports from base, or from
+// http.DefaultTransport when base is nil.
+func NewHttp2FallbackTransport(base *http.Transport) *Http2FallbackTransport {
+	if base == nil {
+		base = http.DefaultTransport.(*http.Transport)
+	}
+
+	http2 := base.Clone()
+	http2.ForceAttemptHTTP2 = true
+
+	return &Http2FallbackTransport{http2: http2, http1: NewHttp1Transport(base)}
+}
+
+func (t *Http2FallbackTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.usingHttp1() {
+		return t.http1.RoundTrip(req)
+	}
+
+	res, err := t.http2.RoundTrip(req)
+	t.record(err)
+	return res, err
+}
+
+// UsingHttp1 reports whether requests are currently sent over HTTP/1.1.
+func (t *Http2FallbackTransport) UsingHttp1() bool {
+	return t.usingHttp1()
+}
+
+// CloseIdleConnections closes idle connections of both transports.
+func (t *Http2FallbackTransport) CloseIdleConnections() {
+	for _, rt := range []http.RoundTripper{t.http2, t.http1} {
+		if c, ok := rt.(interface{ CloseIdleConnections() }); ok {
+			c.CloseIdleConnections()
+		}
+	}
+}
+
+func (t *Http2FallbackTransport) usingHttp1() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return time.Now().Before(t.fallbackUntil)
+}
+
+func (t *Http2FallbackTransport) record(err error) {
+	threshold := t.Threshold
+	if threshold <= 0 {
+		threshold = 3
+	}
+	cooldown := t.Cooldown
+	if cooldown <= 0 {
+		cooldown = 5 * time.Minute
+	}
+
+	t.mu.Lock()
+	if err == nil || !isHttp2Failure(err) {
+		t.failures = 0
+		t.mu.Unlock()
+		return
+	}
+
+	t.failures++
+	fallback := t.failures >= threshold
+	if fallback {
+		t.failures = 0
+		t.fallbackUntil = time.Now().Add(cooldown)
+	}
+	t.mu.Unlock()
+
+	if fallback && t.OnFallback != nil {
+		t.OnFallback(err)
+	}
+}
+
+func isHttp2Failure(err error) bool {
+	return strings.Contains(err.Error(), "http2:") || errors.Is(err, syscall.ECONNRESET)
+}
+
+// ErrHostQueueTimeout is returned when a request waited longer than
+// HostLimitTransport.QueueTimeout for a slot.
+var ErrHostQueueTimeout = errors.New("timed out waiting for a per-host connection slot")
+
+// HostLimitTransport caps concurrent requests per host. Excess requests queue
+// until a slot frees, their context ends, or QueueTimeout passes, so a burst
+// of goroutines does not open a stampede of connections. A slot is held until
+// the response body is closed.
+type HostLimitTransport struct {
+	// Next sends the requests. Defaults to http.DefaultTransport.
+	Next http.RoundTripper
+
+	// MaxPerHost is the concurrency cap. Zero means no cap.
+	MaxPerHost int
+
+	// QueueTimeout bounds the wait for a slot. Zero waits as long as the
+	// request's context allows.
+	QueueTimeout time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]chan struct{}
+}
+
+func (t *HostLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if t.MaxPerHost <= 0 {
+		return next.RoundTrip(req)
+	}
+
+	slots := t.slots(req.URL.Host)
+
+	var timeout <-chan time.Time
+	if t.QueueTimeout > 0 {
+		timer := time.NewTimer(t.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case slots <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	case <-timeout:
+		return nil, ErrHostQueueTimeout
+	}
+
+	var once sync.Once
+	release := func() { once.Do(func() { <-slots }) }
+
+	res, err := next.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	res.Body = &finishingBody{ReadCloser: res.Body, finish: release}
+	return res, nil
+}
+
+func (t *HostLimitTransport) slots(host string) chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.hosts == nil {
+		t.hosts = map[string]chan struct{}{}
+	}
+	slots, ok := t.hosts[host]
+	if !ok {
+		slots = make(chan struct{}, t.MaxPerHost)
+		t.hosts[host] = slots
+	}
+	return slots
+}
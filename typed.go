@@ -0,0 +1,71 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"net/http"
+)
+
+// Call is the typed form of the verb helpers: it sends request and returns
+// the decoded response, e.g.
+//
+//	order, err := core.Call[CreateOrderRequest, Order](ctx, client, "/orders", core.EmptyQueryParams, http.MethodPost, []int{http.StatusOK}, req, headersFunc)
+func Call[TReq, TResp any](
+	ctx context.Context,
+	client Client,
+	path,
+	query,
+	httpMethod string,
+	expectedHttpStatusCodes []int,
+	request TReq,
+	headersFunc HeaderFunc,
+) (TResp, error) {
+	var response TResp
+	err := call(ctx, client, path, query, httpMethod, expectedHttpStatusCodes, request, &response, headersFunc)
+	return response, err
+}
+
+// GetAs is HttpGet returning the decoded response.
+func GetAs[TResp any](ctx context.Context, client Client, path, query string, headersFunc HeaderFunc) (TResp, error) {
+	var response TResp
+	err := call(ctx, client, path, query, http.MethodGet, []int{http.StatusOK}, nil, &response, headersFunc)
+	return response, err
+}
+
+// PostAs is HttpPost returning the decoded response.
+func PostAs[TReq, TResp any](ctx context.Context, client Client, path, query string, request TReq, headersFunc HeaderFunc) (TResp, error) {
+	return Call[TReq, TResp](ctx, client, path, query, http.MethodPost, []int{http.StatusOK}, request, headersFunc)
+}
+
+// PutAs is HttpPut returning the decoded response.
+func PutAs[TReq, TResp any](ctx context.Context, client Client, path, query string, request TReq, headersFunc HeaderFunc) (TResp, error) {
+	return Call[TReq, TResp](ctx, client, path, query, http.MethodPut, []int{http.StatusOK}, request, headersFunc)
+}
+
+// PatchAs is HttpPatch returning the decoded response.
+func PatchAs[TReq, TResp any](ctx context.Context, client Client, path, query string, request TReq, headersFunc HeaderFunc) (TResp, error) {
+	return Call[TReq, TResp](ctx, client, path, query, http.MethodPatch, []int{http.StatusOK}, request, headersFunc)
+}
+
+// DeleteAs is HttpDelete without a request body, returning the decoded
+// response.
+func DeleteAs[TResp any](ctx context.Context, client Client, path, query string, headersFunc HeaderFunc) (TResp, error) {
+	var response TResp
+	err := call(ctx, client, path, query, http.MethodDelete, []int{http.StatusOK}, nil, &response, headersFunc)
+	return response, err
+}
@@ -0,0 +1,229 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// VaultCredentialsProvider reads credentials from a HashiCorp Vault KV v2
+// secret holding the same fields as a credentials file, and renews its own
+// token before the lease runs out.
+type VaultCredentialsProvider struct {
+	// Address is the Vault server, e.g. "https://vault.example.com:8200".
+	Address string
+
+	// Token authenticates to Vault.
+	Token string
+
+	// Namespace, when set, is sent as X-Vault-Namespace.
+	Namespace string
+
+	// Mount is the KV v2 secrets engine mount. Defaults to "secret".
+	Mount string
+
+	// Path is the secret's path within the mount.
+	Path string
+
+	// RenewBefore is how long before the token expires it is renewed.
+	// Defaults to 5 minutes.
+	RenewBefore time.Duration
+
+	// HttpClient sends the Vault requests. Defaults to http.DefaultClient.
+	HttpClient *http.Client
+
+	// tokenMu serializes token lookups and renewals, so concurrent Retrieve
+	// calls make one request to Vault between them.
+	tokenMu sync.Mutex
+
+	mu             sync.Mutex
+	tokenChecked   bool
+	tokenRenewable bool
+	tokenExpiresAt time.Time
+}
+
+func (p *VaultCredentialsProvider) Retrieve(ctx context.Context) (*Credentials, error) {
+	if err := p.ensureToken(ctx); err != nil {
+		return nil, err
+	}
+
+	path := fmt.Sprintf("/v1/%s/data/%s", strings.Trim(p.mount(), "/"), strings.TrimLeft(p.Path, "/"))
+	var secret struct {
+		Data struct {
+			Data json.RawMessage `json:"data"`
+		} `json:"data"`
+	}
+	if err := p.do(ctx, http.MethodGet, path, nil, &secret); err != nil {
+		return nil, err
+	}
+	if len(secret.Data.Data) == 0 || string(secret.Data.Data) == "null" {
+		return nil, fmt.Errorf("vault secret %s has no data", p.Path)
+	}
+
+	credentials, err := parseCredentials(secret.Data.Data)
+	if err != nil {
+		return nil, fmt.Errorf("vault secret %s: %w", p.Path, err)
+	}
+	return credentials, nil
+}
+
+// RenewToken renews the token and records its new expiry.
+func (p *VaultCredentialsProvider) RenewToken(ctx context.Context) error {
+	p.tokenMu.Lock()
+	defer p.tokenMu.Unlock()
+	return p.renewToken(ctx)
+}
+
+// renewToken renews the token. p.tokenMu must be held.
+func (p *VaultCredentialsProvider) renewToken(ctx context.Context) error {
+	var renewed struct {
+		Auth struct {
+			LeaseDuration int  `json:"lease_duration"`
+			Renewable     bool `json:"renewable"`
+		} `json:"auth"`
+	}
+	if err := p.do(ctx, http.MethodPost, "/v1/auth/token/renew-self", []byte("{}"), &renewed); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.tokenChecked = true
+	p.tokenRenewable = renewed.Auth.Renewable
+	p.tokenExpiresAt = expiryAfter(renewed.Auth.LeaseDuration)
+	return nil
+}
+
+// TokenExpiresAt returns when the token expires, or the zero time if it does
+// not or has not been looked up yet.
+func (p *VaultCredentialsProvider) TokenExpiresAt() time.Time {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.tokenExpiresAt
+}
+
+// ensureToken looks the token up on first use and renews it when it is
+// renewable and close to expiry.
+func (p *VaultCredentialsProvider) ensureToken(ctx context.Context) error {
+	p.tokenMu.Lock()
+	defer p.tokenMu.Unlock()
+
+	p.mu.Lock()
+	checked := p.tokenChecked
+	p.mu.Unlock()
+
+	if !checked {
+		var lookup struct {
+			Data struct {
+				Ttl       int  `json:"ttl"`
+				Renewable bool `json:"renewable"`
+			} `json:"data"`
+		}
+		if err := p.do(ctx, http.MethodGet, "/v1/auth/token/lookup-self", nil, &lookup); err != nil {
+			return err
+		}
+		p.mu.Lock()
+		p.tokenChecked = true
+		p.tokenRenewable = lookup.Data.Renewable
+		p.tokenExpiresAt = expiryAfter(lookup.Data.Ttl)
+		p.mu.Unlock()
+	}
+
+	p.mu.Lock()
+	renew := p.tokenRenewable && !p.tokenExpiresAt.IsZero() && time.Until(p.tokenExpiresAt) < p.renewBefore()
+	p.mu.Unlock()
+
+	if renew {
+		return p.renewToken(ctx)
+	}
+	return nil
+}
+
+func (p *VaultCredentialsProvider) do(ctx context.Context, method, path string, body []byte, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(p.Address, "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", p.Token)
+	if p.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.Namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := p.HttpClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("vault %s %s: %w", method, path, err)
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("vault %s %s: %w", method, path, err)
+	}
+	if res.StatusCode != http.StatusOK {
+		var failure struct {
+			Errors []string `json:"errors"`
+		}
+		_ = json.Unmarshal(data, &failure)
+		return fmt.Errorf("vault %s %s: status %d: %s", method, path, res.StatusCode, strings.Join(failure.Errors, "; "))
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("vault %s %s: invalid response", method, path)
+	}
+	return nil
+}
+
+func (p *VaultCredentialsProvider) mount() string {
+	if p.Mount == "" {
+		return "secret"
+	}
+	return p.Mount
+}
+
+func (p *VaultCredentialsProvider) renewBefore() time.Duration {
+	if p.RenewBefore <= 0 {
+		return 5 * time.Minute
+	}
+	return p.RenewBefore
+}
+
+// expiryAfter converts a Vault ttl in seconds to a time, zero meaning the
+// token never expires.
+func expiryAfter(ttl int) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(time.Duration(ttl) * time.Second)
+}
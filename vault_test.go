@@ -0,0 +1,142 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// vaultStub serves token lookup and renewal and one KV v2 secret, counting
+// the token requests it receives.
+type vaultStub struct {
+	ttl      int
+	lookups  atomic.Int32
+	renewals atomic.Int32
+}
+
+func (v *vaultStub) start(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root-token" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v1/auth/token/lookup-self":
+			v.lookups.Add(1)
+			// Give concurrent callers a chance to overlap the lookup.
+			time.Sleep(10 * time.Millisecond)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"ttl": v.ttl, "renewable": true},
+			})
+		case "/v1/auth/token/renew-self":
+			v.renewals.Add(1)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"auth": map[string]interface{}{"lease_duration": 3600, "renewable": true},
+			})
+		case "/v1/kv/data/exchange/trading":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"data": map[string]string{
+					"accessKey":  "access-key",
+					"passphrase": "passphrase",
+					"signingKey": testHmacSecret,
+				}},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[]}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestVaultCredentialsProviderReadsSecret(t *testing.T) {
+	stub := &vaultStub{ttl: 3600}
+	p := &VaultCredentialsProvider{Address: stub.start(t).URL, Token: "root-token", Mount: "kv", Path: "exchange/trading"}
+
+	credentials, err := p.Retrieve(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if credentials.AccessKey != "access-key" || credentials.Passphrase != "passphrase" || credentials.SigningKey != testHmacSecret {
+		t.Fatalf("credentials = %+v, want the secret's fields", credentials)
+	}
+	if expiresAt := p.TokenExpiresAt(); time.Until(expiresAt) < 59*time.Minute {
+		t.Fatalf("TokenExpiresAt = %v, want about an hour from now", expiresAt)
+	}
+	if n := stub.renewals.Load(); n != 0 {
+		t.Fatalf("token renewed %d times while far from expiry, want 0", n)
+	}
+}
+
+func TestVaultCredentialsProviderLooksUpTokenOnce(t *testing.T) {
+	stub := &vaultStub{ttl: 3600}
+	p := &VaultCredentialsProvider{Address: stub.start(t).URL, Token: "root-token", Mount: "kv", Path: "exchange/trading"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := p.Retrieve(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := stub.lookups.Load(); n != 1 {
+		t.Fatalf("token looked up %d times by concurrent Retrieve calls, want 1", n)
+	}
+}
+
+func TestVaultCredentialsProviderRenewsExpiringToken(t *testing.T) {
+	stub := &vaultStub{ttl: 60}
+	p := &VaultCredentialsProvider{Address: stub.start(t).URL, Token: "root-token", Mount: "kv", Path: "exchange/trading"}
+
+	for i := 0; i < 2; i++ {
+		if _, err := p.Retrieve(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := stub.renewals.Load(); n != 1 {
+		t.Fatalf("token renewed %d times, want once since the renewed lease is an hour", n)
+	}
+	if expiresAt := p.TokenExpiresAt(); time.Until(expiresAt) < 59*time.Minute {
+		t.Fatalf("TokenExpiresAt after renewal = %v, want about an hour from now", expiresAt)
+	}
+}
+
+func TestVaultCredentialsProviderReportsVaultErrors(t *testing.T) {
+	stub := &vaultStub{ttl: 3600}
+	p := &VaultCredentialsProvider{Address: stub.start(t).URL, Token: "wrong-token", Path: "exchange/trading"}
+
+	_, err := p.Retrieve(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "status 403: permission denied") {
+		t.Fatalf("Retrieve with a bad token = %v, want Vault's 403 message", err)
+	}
+}
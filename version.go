@@ -0,0 +1,75 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is the release of core-go, in semantic version form. It is updated
+// with each tagged release.
+const Version = "0.2.0"
+
+// CompatibilityCheck returns an error when Version is older than minVersion,
+// e.g. "0.2.0" or "v0.2.0". SDKs call it at init time to require the signing
+// and behavioral fixes they depend on.
+func CompatibilityCheck(minVersion string) error {
+	required, err := parseVersion(minVersion)
+	if err != nil {
+		return err
+	}
+	current, err := parseVersion(Version)
+	if err != nil {
+		return err
+	}
+
+	for i := range current {
+		if current[i] != required[i] {
+			if current[i] < required[i] {
+				return fmt.Errorf("core-go %s is older than the required %s", Version, minVersion)
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// parseVersion parses major.minor.patch, ignoring a leading "v" and any
+// pre-release or build suffix.
+func parseVersion(version string) ([3]int, error) {
+	var parsed [3]int
+
+	v := strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+
+	parts := strings.Split(v, ".")
+	if len(parts) > 3 {
+		return parsed, fmt.Errorf("invalid version: %s", version)
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return parsed, fmt.Errorf("invalid version: %s", version)
+		}
+		parsed[i] = n
+	}
+	return parsed, nil
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright 2024-present Coinbase Global, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// Warmup opens connections to the client's base URL ahead of the first real
+// call, so DNS, TCP, and TLS setup are paid at startup rather than on the
+// first order. It sends connections concurrent unauthenticated requests: HEAD
+// of the base URL, or GET of pingPath when set. Any HTTP response counts as
+// success; only transport failures are returned.
+func Warmup(ctx context.Context, client Client, connections int, pingPath string) error {
+	if connections < 1 {
+		connections = 1
+	}
+
+	method := http.MethodHead
+	if pingPath != "" {
+		method = http.MethodGet
+	}
+	target := client.HttpBaseUrl() + pingPath
+
+	calls := make([]func(ctx context.Context) error, connections)
+	for i := range calls {
+		calls[i] = func(ctx context.Context) error {
+			req, err := http.NewRequestWithContext(ctx, method, target, nil)
+			if err != nil {
+				return err
+			}
+
+			res, err := client.HttpClient().Do(req)
+			if err != nil {
+				return err
+			}
+
+			// Drain so the connection returns to the idle pool.
+			io.Copy(io.Discard, res.Body)
+			return res.Body.Close()
+		}
+	}
+
+	return ParallelCalls(ctx, connections, calls...)
+}